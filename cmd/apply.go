@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
+)
+
+var (
+	applyFile  string
+	applyPrune bool
+
+	applyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Make the account match a YAML redirect spec",
+		Run: func(cmd *cobra.Command, args []string) {
+			doApply(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&applyFile, "file", "", "", "Filename")
+	applyCmd.Flags().BoolVarP(&applyPrune, "prune", "", false, "Delete rules that are not in the spec")
+	applyCmd.MarkFlagRequired("file")
+}
+
+func doApply(ctx context.Context) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	importer.Apply(ctx, &importer.Options{
+		File:   applyFile,
+		Format: "yaml",
+		Prune:  applyPrune,
+		Client: c,
+	})
+}
@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +18,8 @@ var (
 	createResponseType  string
 	createSourceUrls    []string
 	createTargetURL     string
+	createOnConflict    string
+	createHostsFlags    hostFlags
 
 	createCmd = &cobra.Command{
 		Use:   "create",
@@ -22,8 +29,22 @@ var (
 	createRulesCmd = &cobra.Command{
 		Use:   "rule",
 		Short: "A brief description of your command",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return importer.CheckConflictMode(createOnConflict)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			doCreateRule(cmd.Context())
+		},
+	}
+
+	createHostsCmd = &cobra.Command{
+		Use:   "host [name]",
+		Short: "Create a host with the given settings",
+		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			doCreateRule()
+			flagsChanged = getFlagsChanged(cmd)
+			name := args[0]
+			doCreateHost(cmd.Context(), name)
 		},
 	}
 )
@@ -36,14 +57,18 @@ func init() {
 	createRulesCmd.Flags().StringVarP(&createResponseType, "response-type", "", defaultResponseType, "Response type")
 	createRulesCmd.Flags().StringSliceVarP(&createSourceUrls, "source-urls", "", nil, "Source URLs")
 	createRulesCmd.Flags().StringVarP(&createTargetURL, "target-url", "", "", "Target URL")
+	createRulesCmd.Flags().StringVarP(&createOnConflict, "on-conflict", "", importer.ConflictAbort, fmt.Sprintf("Action when a source URL is already used (%s)", strings.Join(importer.ConflictModes, ", ")))
 	createRulesCmd.MarkFlagRequired("source-urls")
 	createRulesCmd.MarkFlagRequired("target-url")
+
+	createCmd.AddCommand(createHostsCmd)
+	createHostsFlags.register(createHostsCmd)
 }
 
-func doCreateRule() {
-	c, err := easyredir.NewClient()
+func doCreateRule(ctx context.Context) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
@@ -54,11 +79,62 @@ func doCreateRule() {
 	rule.Data.Attributes.SourceUrls = createSourceUrls
 	rule.Data.Attributes.TargetURL = createTargetURL
 
-	res, err := c.CreateRule(&rule)
+	s, err := c.Simulator(ctx, easyredir.SourceHosts(rule.Data.Attributes.SourceUrls)...)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	var res *easyredir.Rule
+
+	conflicts := s.Conflicts(&rule)
+
+	switch {
+	case len(conflicts) == 0:
+		res, err = c.CreateRule(ctx, &rule)
+	case createOnConflict == importer.ConflictSkip:
+		conflicts.Print()
+		return
+	case createOnConflict == importer.ConflictMerge && len(conflicts.Owners()) == 1:
+		owner, _ := s.Rule(conflicts.Owners()[0])
+		merged := s.Merge(*owner, &rule)
+		res, err = c.UpdateRule(ctx, &merged)
+	default:
+		conflicts.Print()
+		fmt.Fprintln(os.Stderr)
+		err = &easyredir.ConflictError{Conflicts: conflicts}
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
-	res.Print()
+	if err = res.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
+}
+
+func doCreateHost(ctx context.Context, name string) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	host := easyredir.Host{}
+	host.Data.Attributes.Name = name
+
+	createHostsFlags.apply(&host, flagsChanged)
+
+	res, err := c.CreateHost(ctx, &host)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	if err = res.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
 }
@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -14,12 +15,22 @@ var (
 	}
 
 	deleteRulesCmd = &cobra.Command{
-		Use:   "rule [id]",
+		Use:   "rule [id|source-url]",
 		Short: "A brief description of your command",
 		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			id := args[0]
-			doDeleteRules(id)
+			doDeleteRules(cmd.Context(), id)
+		},
+	}
+
+	deleteHostsCmd = &cobra.Command{
+		Use:   "host [id|name]",
+		Short: "Delete a host that no rule uses any more",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := args[0]
+			doDeleteHosts(cmd.Context(), id)
 		},
 	}
 )
@@ -27,21 +38,51 @@ var (
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 	deleteCmd.AddCommand(deleteRulesCmd)
+	deleteCmd.AddCommand(deleteHostsCmd)
 }
 
-func doDeleteRules(id string) {
-	c, err := easyredir.NewClient()
+func doDeleteRules(ctx context.Context, id string) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
+		return
+	}
+
+	id, err = c.ResolveRule(ctx, id)
+	if err != nil {
+		logError(err)
 		return
 	}
 
 	rule := easyredir.Rule{}
 	rule.Data.ID = id
 
-	_, err = c.RemoveRule(&rule)
+	_, err = c.RemoveRule(ctx, &rule)
+	if err != nil {
+		logError(err)
+		return
+	}
+}
+
+func doDeleteHosts(ctx context.Context, id string) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	id, err = c.ResolveHost(ctx, id)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	host := easyredir.Host{}
+	host.Data.ID = id
+
+	_, err = c.RemoveHost(ctx, &host)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 }
@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -14,12 +15,22 @@ var (
 	}
 
 	describeHostCmd = &cobra.Command{
-		Use:   "host [id]",
+		Use:   "host [id|name]",
 		Short: "A brief description of your command",
 		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			id := args[0]
-			doDescribeHost(id)
+			doDescribeHost(cmd.Context(), id)
+		},
+	}
+
+	describeRuleCmd = &cobra.Command{
+		Use:   "rule [id|source-url]",
+		Short: "Show a rule and its source hosts",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := args[0]
+			doDescribeRule(cmd.Context(), id)
 		},
 	}
 )
@@ -27,21 +38,59 @@ var (
 func init() {
 	rootCmd.AddCommand(describeCmd)
 	describeCmd.AddCommand(describeHostCmd)
+	describeCmd.AddCommand(describeRuleCmd)
 }
 
-func doDescribeHost(id string) {
-	c, err := easyredir.NewClient()
+func doDescribeHost(ctx context.Context, id string) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
+		return
+	}
+
+	id, err = c.ResolveHost(ctx, id)
+	if err != nil {
+		logError(err)
 		return
 	}
 
 	host := easyredir.Host{}
 	host.Data.ID = id
-	err = c.GetHost(&host)
+	err = c.GetHost(ctx, &host)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	if err = host.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
+}
+
+func doDescribeRule(ctx context.Context, id string) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	id, err = c.ResolveRule(ctx, id)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
+		return
+	}
+
+	rule := easyredir.Rule{}
+	rule.Data.ID = id
+	err = c.GetRule(ctx, &rule)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	if err = rule.Output(outputOptions()); err != nil {
+		logError(err)
 		return
 	}
-	host.Print()
 }
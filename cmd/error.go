@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed error_print.tmpl
+var errorPrintTemplate string
+
+// logError logs err and, when it came from the API, renders the response
+// details to stderr so they stay out of any piped output.
+func logError(err error) {
+	var apiErr *easyredir.APIError
+	if errors.As(err, &apiErr) {
+		printAPIError(apiErr)
+	}
+
+	log.Error().Err(err).Msg("")
+}
+
+func printAPIError(e *easyredir.APIError) {
+	fmt.Fprintf(os.Stderr, "%s:\n", text.FgRed.Sprint("ERROR"))
+	fmt.Fprintln(os.Stderr)
+
+	var w bytes.Buffer
+
+	t := template.Must(template.New("").Parse(errorPrintTemplate))
+	t.Execute(&w, e)
+
+	quick.Highlight(os.Stderr, w.String(), "yaml", "terminal256", "pygments")
+
+	fmt.Fprintln(os.Stderr)
+
+	return
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
+)
+
+var (
+	exportFile   string
+	exportFormat string
+
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Write every rule in the account as a redirect spec",
+		Long: `Write every rule in the account as a redirect spec.
+
+The YAML output uses the same schema as import and apply, with each source
+carrying the settings of its host, so the account can be kept under version
+control and reconciled with apply.
+
+The terraform output describes every host and rule as a resource, with import
+blocks that adopt the existing IDs.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			doExport(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportFile, "file", "", "", "Filename (default stdout)")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "", "yaml", fmt.Sprintf("Format (%s)", strings.Join(importer.ExportFormats(), ", ")))
+}
+
+func doExport(ctx context.Context) {
+	// Check the format before the file is created so it is not left empty.
+	known := false
+	for _, f := range importer.ExportFormats() {
+		known = known || f == exportFormat
+	}
+	if !known {
+		logError(fmt.Errorf("unknown format %q, use one of %s", exportFormat, strings.Join(importer.ExportFormats(), ", ")))
+		return
+	}
+
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+
+	if exportFile != "" {
+		f, err := os.Create(exportFile)
+		if err != nil {
+			logError(err)
+			return
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	err = importer.Export(ctx, &importer.Options{
+		Format: exportFormat,
+		Client: c,
+	}, w)
+	if err != nil {
+		logError(err)
+		return
+	}
+}
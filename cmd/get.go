@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -20,59 +21,81 @@ var (
 		Use:   "hosts",
 		Short: "A brief description of your command",
 		Run: func(cmd *cobra.Command, args []string) {
-			doGetHosts()
+			doGetHosts(cmd.Context())
 		},
 	}
 )
 
+var getRuleCmd = &cobra.Command{
+	Use:   "rule [id|source-url]",
+	Short: "Show a single rule",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		doDescribeRule(cmd.Context(), id)
+	},
+}
+
 var getRulesCmd = &cobra.Command{
 	Use:   "rules",
 	Short: "A brief description of your command",
 	Run: func(cmd *cobra.Command, args []string) {
-		doGetRules()
+		doGetRules(cmd.Context())
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(getCmd)
 	getCmd.AddCommand(getHostsCmd)
+	getCmd.AddCommand(getRuleCmd)
 	getCmd.AddCommand(getRulesCmd)
 	getCmd.PersistentFlags().StringVar(&getSourceURL, "source-url", "", "source url")
 	getCmd.PersistentFlags().StringVar(&getTargetURL, "target-url", "", "target url")
 }
 
-func doGetHosts() {
-	c, err := easyredir.NewClient()
+func doGetHosts(ctx context.Context) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
-	o := easyredir.HostsOptions{}
-	hosts, err := c.ListHosts(&o)
+	o := easyredir.HostsOptions{
+		StartingAfter: startingAfter,
+		EndingBefore:  endingBefore,
+	}
+	hosts, err := c.ListHosts(ctx, &o)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
+		return
+	}
+	if err = hosts.Output(outputOptions()); err != nil {
+		logError(err)
 		return
 	}
-	hosts.Print()
 }
 
-func doGetRules() {
-	c, err := easyredir.NewClient()
+func doGetRules(ctx context.Context) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
 	o := easyredir.RulesOptions{
-		SourceURL: getSourceURL,
-		TargetURL: getTargetURL,
+		SourceURL:     getSourceURL,
+		TargetURL:     getTargetURL,
+		StartingAfter: startingAfter,
+		EndingBefore:  endingBefore,
 	}
-	rules, err := c.ListRules(&o)
+	rules, err := c.ListRules(ctx, &o)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
-	rules.Print()
+	if err = rules.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/spf13/cobra"
+)
+
+// hostFlags are the host settings shared by the create and update host
+// commands.
+type hostFlags struct {
+	caseInsensitive         bool
+	slashInsensitive        bool
+	forwardParams           bool
+	forwardPath             bool
+	custom404Body           string
+	responseCode            int
+	responseURL             string
+	httpsUpgrade            bool
+	preventForeignEmbedding bool
+	hstsIncludeSubDomains   bool
+	hstsMaxAge              int
+	hstsPreload             bool
+}
+
+func (f *hostFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&f.caseInsensitive, "case-insensitive", "", defaultCaseInsensitive, "Case insensitive")
+	cmd.Flags().BoolVarP(&f.slashInsensitive, "slash-insensitive", "", defaultSlashInsensitive, "Slash insensitive")
+	cmd.Flags().BoolVarP(&f.forwardParams, "forward-params", "", defaultForwardParams, "Not found forward params")
+	cmd.Flags().BoolVarP(&f.forwardPath, "forward-path", "", defaultForwardPath, "Not found forward path")
+	cmd.Flags().StringVarP(&f.custom404Body, "custom-404-body", "", defaultCustom404Body, "Not found custom 404 body")
+	cmd.Flags().IntVarP(&f.responseCode, "response-code", "", defaultResponseCode, "Not found response code")
+	cmd.Flags().StringVarP(&f.responseURL, "response-url", "", defaultResponseURL, "Not found response URL")
+	cmd.Flags().BoolVarP(&f.httpsUpgrade, "https-upgrade", "", defaultHTTPSUpgrade, "HTTPS upgrade")
+	cmd.Flags().BoolVarP(&f.preventForeignEmbedding, "prevent-foreign-embedding", "", defaultPreventForeignEmbedding, "Prevent foreign embedding")
+	cmd.Flags().BoolVarP(&f.hstsIncludeSubDomains, "hsts-include-sub-domains", "", defaultHSTSIncludeSubDomains, "HSTS include sub domains")
+	cmd.Flags().IntVarP(&f.hstsMaxAge, "hsts-max-age", "", defaultHSTSMaxAge, "HSTS max age")
+	cmd.Flags().BoolVarP(&f.hstsPreload, "hsts-preload", "", defaultHSTSPreload, "HSTS preload")
+}
+
+// apply copies only the flags that were set on the command line, leaving
+// every other setting as it is on the host.
+func (f *hostFlags) apply(host *easyredir.Host, changed []string) {
+	if flagIn("case-insensitive", changed) {
+		host.Data.Attributes.MatchOptions.CaseInsensitive = f.caseInsensitive
+	}
+	if flagIn("slash-insensitive", changed) {
+		host.Data.Attributes.MatchOptions.SlashInsensitive = f.slashInsensitive
+	}
+	if flagIn("forward-params", changed) {
+		host.Data.Attributes.NotFoundAction.ForwardParams = f.forwardParams
+	}
+	if flagIn("forward-path", changed) {
+		host.Data.Attributes.NotFoundAction.ForwardPath = f.forwardPath
+	}
+	if flagIn("custom-404-body", changed) {
+		host.Data.Attributes.NotFoundAction.Custom404Body = f.custom404Body
+	}
+	if flagIn("response-code", changed) {
+		host.Data.Attributes.NotFoundAction.ResponseCode = f.responseCode
+	}
+	if flagIn("response-url", changed) {
+		host.Data.Attributes.NotFoundAction.ResponseURL = f.responseURL
+	}
+	if flagIn("https-upgrade", changed) {
+		host.Data.Attributes.Security.HTTPSUpgrade = f.httpsUpgrade
+	}
+	if flagIn("prevent-foreign-embedding", changed) {
+		host.Data.Attributes.Security.PreventForeignEmbedding = f.preventForeignEmbedding
+	}
+	if flagIn("hsts-include-sub-domains", changed) {
+		host.Data.Attributes.Security.HstsIncludeSubDomains = f.hstsIncludeSubDomains
+	}
+	if flagIn("hsts-max-age", changed) {
+		host.Data.Attributes.Security.HstsMaxAge = f.hstsMaxAge
+	}
+	if flagIn("hsts-preload", changed) {
+		host.Data.Attributes.Security.HstsPreload = f.hstsPreload
+	}
+
+	return
+}
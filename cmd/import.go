@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	"github.com/mikelorant/easyredir-cli/internal/importer"
 )
 
 var (
-	importFile    string
-	importFormat  string
-	importPreview bool
+	importFile     string
+	importFormat   string
+	importPreview  bool
+	importConflict string
+	importHost     string
+	importPath     string
 
 	importCmd = &cobra.Command{
 		Use:   "import",
@@ -19,8 +26,11 @@ var (
 	importRulesCmd = &cobra.Command{
 		Use:   "rules",
 		Short: "A brief description of your command",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return importer.CheckConflictMode(importConflict)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			doImportRules()
+			doImportRules(cmd.Context())
 		},
 	}
 )
@@ -30,14 +40,28 @@ func init() {
 	importCmd.AddCommand(importRulesCmd)
 	importRulesCmd.Flags().BoolVarP(&importPreview, "preview", "", false, "Preview")
 	importRulesCmd.Flags().StringVarP(&importFile, "file", "", "", "Filename")
-	importRulesCmd.Flags().StringVarP(&importFormat, "format", "", "", "Filename")
+	importRulesCmd.Flags().StringVarP(&importFormat, "format", "", "", fmt.Sprintf("Format (%s), detected from the file when not set", strings.Join(importer.Formats(), ", ")))
+	importRulesCmd.Flags().StringVarP(&importConflict, "on-conflict", "", importer.ConflictAbort, fmt.Sprintf("Action when a source URL is already used (%s)", strings.Join(importer.ConflictModes, ", ")))
+	importRulesCmd.Flags().StringVarP(&importHost, "host", "", "", "Host name for sources in files that do not name one, such as .htaccess")
+	importRulesCmd.Flags().StringVarP(&importPath, "path", "", "", "Variable and keys holding the redirects in Puppet manifests (default $controllers.redirects)")
 	importRulesCmd.MarkFlagRequired("file")
 }
 
-func doImportRules() {
-	importer.Import(&importer.Options{
-		File:    importFile,
-		Format:  importFormat,
-		Preview: importPreview,
+func doImportRules(ctx context.Context) {
+	// A preview never reaches the API so it does not need credentials.
+	c, err := newClient()
+	if err != nil && !importPreview {
+		logError(err)
+		return
+	}
+
+	importer.Import(ctx, &importer.Options{
+		File:       importFile,
+		Format:     importFormat,
+		Preview:    importPreview,
+		Host:       importHost,
+		Path:       importPath,
+		OnConflict: importConflict,
+		Client:     c,
 	})
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+var (
+	lintChainsFile string
+
+	lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Check rules for problems",
+	}
+
+	lintChainsCmd = &cobra.Command{
+		Use:   "chains",
+		Short: "Find redirect chains, loops and targets without a rule",
+		Long: `Find redirect chains, loops and targets without a rule.
+
+Every source URL is followed through the rules until it leaves the hosts
+served by EasyRedir. Sources that take more than one redirect, revisit a URL
+or land on a host's not found action are reported.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			doLintChains(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.AddCommand(lintChainsCmd)
+	lintChainsCmd.Flags().StringVarP(&lintChainsFile, "file", "", "", "Filename")
+}
+
+func doLintChains(ctx context.Context) {
+	var c *easyredir.Client
+
+	if lintChainsFile == "" {
+		var err error
+
+		c, err = newClient()
+		if err != nil {
+			logError(err)
+			return
+		}
+	}
+
+	chains, err := importer.Chains(ctx, &importer.Options{
+		File:   lintChainsFile,
+		Format: "yaml",
+		Client: c,
+	})
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	if len(chains) == 0 {
+		fmt.Println("No redirect chains found.")
+		return
+	}
+
+	chains.Print()
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
+)
+
+var (
+	planFile  string
+	planPrune bool
+
+	planCmd = &cobra.Command{
+		Use:   "plan",
+		Short: "Show the changes needed to make the account match a YAML redirect spec",
+		Run: func(cmd *cobra.Command, args []string) {
+			doPlan(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().StringVarP(&planFile, "file", "", "", "Filename")
+	planCmd.Flags().BoolVarP(&planPrune, "prune", "", false, "Delete rules that are not in the spec")
+	planCmd.MarkFlagRequired("file")
+}
+
+func doPlan(ctx context.Context) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	importer.Plan(ctx, &importer.Options{
+		File:   planFile,
+		Format: "yaml",
+		Prune:  planPrune,
+		Client: c,
+	})
+}
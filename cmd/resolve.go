@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+var (
+	resolveFile string
+
+	resolveCmd = &cobra.Command{
+		Use:     "resolve <url>",
+		Aliases: []string{"test"},
+		Short:   "Show how a URL would be redirected",
+		Long: `Show how a URL would be redirected without sending a request to it.
+
+Rules and host settings are read from the account, or from a YAML redirect
+spec when --file is given.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			doResolve(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.Flags().StringVarP(&resolveFile, "file", "", "", "Filename")
+}
+
+func doResolve(ctx context.Context, url string) {
+	var c *easyredir.Client
+
+	if resolveFile == "" {
+		var err error
+
+		c, err = newClient()
+		if err != nil {
+			logError(err)
+			return
+		}
+	}
+
+	res, err := importer.Resolve(ctx, &importer.Options{
+		File:   resolveFile,
+		Format: "yaml",
+		Client: c,
+	}, url)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	res.Print()
+}
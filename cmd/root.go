@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -32,6 +39,9 @@ var (
 	startingAfter string
 	endingBefore  string
 
+	outputFormat   string
+	outputTemplate string
+
 	flagsChanged []string
 
 	defaultSourceURLS []string = []string{}
@@ -42,8 +52,19 @@ var rootCmd = &cobra.Command{
 	Short: "A brief description of your application",
 }
 
+// Execute cancels the command context on the first interrupt so in-flight
+// requests stop cleanly. A second interrupt terminates immediately.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+
 	if err != nil {
 		os.Exit(1)
 	}
@@ -55,6 +76,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.easyredir.yaml)")
 	rootCmd.PersistentFlags().StringVar(&startingAfter, "starting-after", "", "starting after")
 	rootCmd.PersistentFlags().StringVar(&endingBefore, "ending-before", "", "ending before")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", fmt.Sprintf("Output format (%s)", strings.Join(easyredir.OutputFormats, ", ")))
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template used with --output template")
 
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 }
@@ -74,3 +97,33 @@ func initConfig() {
 	viper.AutomaticEnv()
 	viper.ReadInConfig()
 }
+
+// newClient builds an API client from the api.key, api.secret and optional
+// api.url configuration values.
+func newClient() (*easyredir.Client, error) {
+	opts := []easyredir.Option{
+		easyredir.WithAPIKey(viper.GetString("api.key"), viper.GetString("api.secret")),
+		easyredir.WithLogger(log.Logger),
+	}
+
+	if u := viper.GetString("api.url"); u != "" {
+		opts = append(opts, easyredir.WithBaseURL(u))
+	}
+
+	return easyredir.NewClient(opts...)
+}
+
+// outputOptions also accepts the template inline as --output template=...
+func outputOptions() *easyredir.OutputOptions {
+	o := &easyredir.OutputOptions{
+		Format:   outputFormat,
+		Template: outputTemplate,
+	}
+
+	if strings.HasPrefix(o.Format, easyredir.OutputTemplate+"=") {
+		o.Template = strings.TrimPrefix(o.Format, easyredir.OutputTemplate+"=")
+		o.Format = easyredir.OutputTemplate
+	}
+
+	return o
+}
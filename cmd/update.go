@@ -1,31 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/internal/importer"
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 var (
-	updateRulesForwardParams           bool
-	updateRulesForwardPath             bool
-	updateRulesResponseType            string
-	updateRulesSourceURLs              []string
-	updateRulesTargetURL               string
-	updateHostsCaseInsensitive         bool
-	updateHostsSlashInsensitive        bool
-	updateHostsForwardParams           bool
-	updateHostsForwardPath             bool
-	updateHostsCustom404Body           string
-	updateHostsResponseCode            int
-	updateHostsResponseURL             string
-	updateHostsHTTPSUpgrade            bool
-	updateHostsPreventForeignEmbedding bool
-	updateHostsHSTSIncludeSubDomains   bool
-	updateHostsHSTSMaxAge              int
-	updateHostsHSTSPreload             bool
+	updateRulesForwardParams bool
+	updateRulesForwardPath   bool
+	updateRulesResponseType  string
+	updateRulesSourceURLs    []string
+	updateRulesTargetURL     string
+	updateRulesOnConflict    string
+	updateHostsFlags         hostFlags
 
 	updateCmd = &cobra.Command{
 		Use:   "update",
@@ -33,24 +28,27 @@ var (
 	}
 
 	updateRulesCmd = &cobra.Command{
-		Use:   "rule [id]",
+		Use:   "rule [id|source-url]",
 		Short: "A brief description of your command",
 		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return importer.CheckConflictMode(updateRulesOnConflict)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			flagsChanged = getFlagsChanged(cmd)
 			id := args[0]
-			doUpdateRules(id)
+			doUpdateRules(cmd.Context(), id)
 		},
 	}
 
 	updateHostsCmd = &cobra.Command{
-		Use:   "host [id]",
+		Use:   "host [id|name]",
 		Short: "A brief description of your command",
 		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			flagsChanged = getFlagsChanged(cmd)
 			id := args[0]
-			doUpdateHosts(id)
+			doUpdateHosts(cmd.Context(), id)
 		},
 	}
 )
@@ -64,48 +62,36 @@ func init() {
 	updateRulesCmd.Flags().StringVarP(&updateRulesResponseType, "response-type", "", defaultResponseType, "Response type")
 	updateRulesCmd.Flags().StringSliceVarP(&updateRulesSourceURLs, "source-urls", "", defaultSourceURLS, "Source URLs")
 	updateRulesCmd.Flags().StringVarP(&updateRulesTargetURL, "target-url", "", defaultTargetURL, "Target URL")
+	updateRulesCmd.Flags().StringVarP(&updateRulesOnConflict, "on-conflict", "", importer.ConflictAbort, fmt.Sprintf("Action when a source URL is already used (%s)", strings.Join(importer.ConflictModes, ", ")))
 	updateRulesCmd.MarkFlagRequired("id")
 
 	updateCmd.AddCommand(updateHostsCmd)
-	updateHostsCmd.Flags().BoolVarP(&updateHostsCaseInsensitive, "case-insensitive", "", defaultCaseInsensitive, "Case insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsSlashInsensitive, "slash-insensitive", "", defaultSlashInsensitive, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsForwardParams, "forward-params", "", defaultForwardParams, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsForwardPath, "forward-path", "", defaultForwardPath, "Slash insensitive")
-	updateHostsCmd.Flags().StringVarP(&updateHostsCustom404Body, "custom-404-body", "", defaultCustom404Body, "Slash insensitive")
-	updateHostsCmd.Flags().IntVarP(&updateHostsResponseCode, "response-code", "", defaultResponseCode, "Slash insensitive")
-	updateHostsCmd.Flags().StringVarP(&updateHostsResponseURL, "response-url", "", defaultResponseURL, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsHTTPSUpgrade, "https-upgrade", "", defaultHTTPSUpgrade, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsPreventForeignEmbedding, "prevent-foreign-embedding", "", defaultPreventForeignEmbedding, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsHSTSIncludeSubDomains, "hsts-include-sub-domains", "", defaultHSTSIncludeSubDomains, "Slash insensitive")
-	updateHostsCmd.Flags().IntVarP(&updateHostsHSTSMaxAge, "hsts-max-age", "", defaultHSTSMaxAge, "Slash insensitive")
-	updateHostsCmd.Flags().BoolVarP(&updateHostsHSTSPreload, "hsts-preload", "", defaultHSTSPreload, "Slash insensitive")
+	updateHostsFlags.register(updateHostsCmd)
 	updateHostsCmd.MarkFlagRequired("id")
 }
 
-func doUpdateRules(id string) {
-	c, err := easyredir.NewClient()
+func doUpdateRules(ctx context.Context, id string) {
+	c, err := newClient()
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
+		return
+	}
+
+	id, err = c.ResolveRule(ctx, id)
+	if err != nil {
+		logError(err)
+		return
 	}
 
 	rule := easyredir.Rule{}
 	rule.Data.ID = id
 
-	rules, err := c.ListRules(&easyredir.RulesOptions{})
-	if err != nil {
-		log.Error().Err(err).Msg("Unable to list rules.")
+	if err = c.GetRule(ctx, &rule); err != nil {
+		logError(err)
+		return
 	}
 
-	for _, r := range rules.Data {
-		if r.ID == rule.Data.ID {
-			rule.Data.Attributes.ForwardParams = r.Attributes.ForwardParams
-			rule.Data.Attributes.ForwardPath = r.Attributes.ForwardPath
-			rule.Data.Attributes.ResponseType = r.Attributes.ResponseType
-			rule.Data.Attributes.SourceUrls = r.Attributes.SourceURLs
-			rule.Data.Attributes.TargetURL = r.Attributes.TargetURL
-			break
-		}
-	}
+	original := rule
 
 	if flagIn("forward-params", flagsChanged) {
 		rule.Data.Attributes.ForwardParams = updateRulesForwardParams
@@ -123,71 +109,101 @@ func doUpdateRules(id string) {
 		rule.Data.Attributes.TargetURL = updateRulesTargetURL
 	}
 
-	res, err := c.UpdateRule(&rule)
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return
+	// Only new source URLs can conflict with another rule.
+	s := &easyredir.Simulator{}
+	if flagIn("source-urls", flagsChanged) {
+		if s, err = c.Simulator(ctx, easyredir.SourceHosts(rule.Data.Attributes.SourceUrls)...); err != nil {
+			logError(err)
+			return
+		}
 	}
 
-	res.Print()
-}
+	var res *easyredir.Rule
 
-func doUpdateHosts(id string) {
-	c, err := easyredir.NewClient()
+	conflicts := s.Conflicts(&rule)
+
+	switch {
+	case len(conflicts) == 0:
+		res, err = c.UpdateRule(ctx, &rule)
+	case updateRulesOnConflict == importer.ConflictSkip:
+		conflicts.Print()
+		return
+	case updateRulesOnConflict == importer.ConflictMerge && len(conflicts.Owners()) == 1:
+		res, err = mergeRule(ctx, c, s, conflicts.Owners()[0], original, &rule)
+	default:
+		conflicts.Print()
+		fmt.Fprintln(os.Stderr)
+		err = &easyredir.ConflictError{Conflicts: conflicts}
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
-	host := easyredir.Host{}
-	host.Data.ID = id
+	if err = res.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
+}
 
-	c.GetHost(&host)
+// mergeRule folds the updated rule into the rule that owns its conflicting
+// source URLs. The rule is removed first so the owner can take its other
+// source URLs, and created again if the owner cannot be updated.
+func mergeRule(ctx context.Context, c *easyredir.Client, s *easyredir.Simulator, ownerID string, original easyredir.Rule, rule *easyredir.Rule) (*easyredir.Rule, error) {
+	owner, _ := s.Rule(ownerID)
+	merged := s.Merge(*owner, rule)
 
-	if flagIn("case-insensitive", flagsChanged) {
-		host.Data.Attributes.MatchOptions.CaseInsensitive = updateHostsCaseInsensitive
-	}
-	if flagIn("slash-insensitive", flagsChanged) {
-		host.Data.Attributes.MatchOptions.SlashInsensitive = updateHostsSlashInsensitive
+	if _, err := c.RemoveRule(ctx, rule); err != nil {
+		return nil, err
 	}
-	if flagIn("forward-params", flagsChanged) {
-		host.Data.Attributes.NotFoundAction.ForwardParams = updateHostsForwardParams
-	}
-	if flagIn("forward-path", flagsChanged) {
-		host.Data.Attributes.NotFoundAction.ForwardPath = updateHostsForwardPath
-	}
-	if flagIn("custom-404-body", flagsChanged) {
-		host.Data.Attributes.NotFoundAction.Custom404Body = updateHostsCustom404Body
-	}
-	if flagIn("response-code", flagsChanged) {
-		host.Data.Attributes.NotFoundAction.ResponseCode = updateHostsResponseCode
-	}
-	if flagIn("response-url", flagsChanged) {
-		host.Data.Attributes.NotFoundAction.ResponseURL = updateHostsResponseURL
-	}
-	if flagIn("https-upgrade", flagsChanged) {
-		host.Data.Attributes.Security.HTTPSUpgrade = updateHostsHTTPSUpgrade
+
+	res, err := c.UpdateRule(ctx, &merged)
+	if err == nil {
+		return res, nil
 	}
-	if flagIn("prevent-foreign-embedding", flagsChanged) {
-		host.Data.Attributes.Security.PreventForeignEmbedding = updateHostsPreventForeignEmbedding
+
+	restore := easyredir.Rule{}
+	restore.Data.Attributes = original.Data.Attributes
+	if _, rerr := c.CreateRule(ctx, &restore); rerr != nil {
+		return nil, fmt.Errorf("%w, rule %s could not be created again: %v", err, rule.Data.ID, rerr)
 	}
-	if flagIn("hsts-include-sub-domains", flagsChanged) {
-		host.Data.Attributes.Security.HstsIncludeSubDomains = updateHostsHSTSIncludeSubDomains
+
+	return nil, fmt.Errorf("%w, rule %s was created again with a new ID", err, rule.Data.ID)
+}
+
+func doUpdateHosts(ctx context.Context, id string) {
+	c, err := newClient()
+	if err != nil {
+		logError(err)
+		return
 	}
-	if flagIn("hsts-max-age", flagsChanged) {
-		host.Data.Attributes.Security.HstsMaxAge = updateHostsHSTSMaxAge
+
+	id, err = c.ResolveHost(ctx, id)
+	if err != nil {
+		logError(err)
+		return
 	}
-	if flagIn("hsts-preload", flagsChanged) {
-		host.Data.Attributes.Security.HstsPreload = updateHostsHSTSPreload
+
+	host := easyredir.Host{}
+	host.Data.ID = id
+
+	if err = c.GetHost(ctx, &host); err != nil {
+		logError(err)
+		return
 	}
 
-	res, err := c.UpdateHost(&host)
+	updateHostsFlags.apply(&host, flagsChanged)
+
+	res, err := c.UpdateHost(ctx, &host)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logError(err)
 		return
 	}
 
-	res.Print()
+	if err = res.Output(outputOptions()); err != nil {
+		logError(err)
+		return
+	}
 }
 
 func getFlagsChanged(cmd *cobra.Command) (flags []string) {
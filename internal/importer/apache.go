@@ -0,0 +1,601 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed apache_print.tmpl
+var apachePrintTemplate string
+
+// apacheContent matches a VirtualHost or a redirecting directive.
+var apacheContent = regexp.MustCompile(`(?mi)^\s*(<VirtualHost\b|Redirect(Match|Permanent|Temp)?\s|RewriteRule\s)`)
+
+func init() {
+	Register(Format{
+		Name: "apache",
+		New:  func() Importer { return &ApacheRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return filepath.Base(file) == ".htaccess" || apacheContent.Match(content)
+		},
+	})
+}
+
+type ApacheRedirects []ApacheRedirect
+
+type ApacheRedirect struct {
+	Line      int
+	Directive string
+	simpleRedirect
+}
+
+// apacheDirective is a single line of Apache configuration after
+// continuation lines are joined.
+type apacheDirective struct {
+	Name string
+	Args []string
+	Line int
+}
+
+// apacheScope is the configuration that applies to one set of host names: a
+// VirtualHost, or the file itself for .htaccess and server wide settings.
+type apacheScope struct {
+	Names      []string
+	Directives []apacheDirective
+}
+
+var (
+	// apacheHostCondition matches a RewriteCond pattern for a single host
+	// name, such as ^www\.example\.com$.
+	apacheHostCondition = regexp.MustCompile(`^\^?((?:[a-zA-Z0-9-]|\\\.)+)\$?$`)
+)
+
+// Load reads Redirect, RedirectMatch and RewriteRule directives. Directives
+// outside a VirtualHost, as in .htaccess files, use the given host name.
+func (rs *ApacheRedirects) Load(file string, host string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	defer f.Close()
+
+	scopes, err := parseApache(file, f)
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
+	}
+
+	if host != "" {
+		scopes[0].Names = []string{apacheServerName(host)}
+	}
+
+	seen := make(map[string]bool)
+
+	for _, scope := range scopes {
+		for _, r := range apacheScopeRedirects(file, scope) {
+			// The same redirect is often repeated for the http and https
+			// virtual hosts.
+			key := strings.Join(r.SourceURLs, ",") + " " + r.TargetURL
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			*rs = append(*rs, r)
+		}
+	}
+
+	return
+}
+
+func apacheScopeRedirects(file string, scope apacheScope) (rs ApacheRedirects) {
+	conditions := []apacheDirective{}
+
+	for _, d := range scope.Directives {
+		var (
+			r   *ApacheRedirect
+			err error
+		)
+
+		switch strings.ToLower(d.Name) {
+		case "rewritecond":
+			conditions = append(conditions, d)
+			continue
+		case "rewriterule":
+			r, err = apacheRewriteRule(file, d, conditions, scope.Names)
+			conditions = nil
+		case "redirect", "redirectpermanent", "redirecttemp":
+			r, err = apacheRedirect(d, scope.Names)
+		case "redirectmatch":
+			r, err = apacheRedirectMatch(d, scope.Names)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Warn().Msgf("%s:%d: %s", file, d.Line, err)
+			continue
+		}
+
+		if r != nil {
+			rs = append(rs, *r)
+		}
+	}
+
+	return rs
+}
+
+// apacheRedirect converts the mod_alias Redirect directives. They match a
+// path prefix and keep the rest of the path and the query string.
+func apacheRedirect(d apacheDirective, names []string) (*ApacheRedirect, error) {
+	args := d.Args
+
+	status := "temp"
+	switch strings.ToLower(d.Name) {
+	case "redirectpermanent":
+		status = "permanent"
+	case "redirect":
+		if len(args) == 3 || (len(args) == 2 && !strings.HasPrefix(args[0], "/")) {
+			status, args = args[0], args[1:]
+		}
+	}
+
+	r, err := apacheRedirectStatus(d, status)
+	if err != nil || r == nil {
+		return r, err
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s needs a path and a URL", d.Name)
+	}
+
+	if !strings.Contains(args[1], "://") {
+		return nil, fmt.Errorf("%s target %s is not an absolute URL", d.Name, args[1])
+	}
+
+	r.ForwardPath = true
+	r.ForwardParams = !strings.Contains(args[1], "?")
+	r.TargetURL = args[1]
+
+	return r, apacheSources(r, names, args[0], false)
+}
+
+func apacheRedirectMatch(d apacheDirective, names []string) (*ApacheRedirect, error) {
+	args := d.Args
+
+	status := "temp"
+	if len(args) == 3 {
+		status, args = args[0], args[1:]
+	}
+
+	r, err := apacheRedirectStatus(d, status)
+	if err != nil || r == nil {
+		return r, err
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s needs a pattern and a URL", d.Name)
+	}
+
+	path, forwardPath, ok := rewritePath(args[0])
+	if !ok {
+		return nil, fmt.Errorf("%s pattern %s cannot be converted", d.Name, args[0])
+	}
+
+	target, err := rewriteTarget(args[1], forwardPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", d.Name, err)
+	}
+
+	r.ForwardPath = forwardPath
+	r.ForwardParams = !strings.Contains(target, "?")
+	r.TargetURL = target
+
+	return r, apacheSources(r, names, path, false)
+}
+
+func apacheRedirectStatus(d apacheDirective, status string) (*ApacheRedirect, error) {
+	code, err := redirectStatus(status)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", d.Name, err)
+	}
+
+	responseType, ok := responseTypeForStatus(code)
+	if !ok {
+		return nil, fmt.Errorf("%s with status %d is not a redirect", d.Name, code)
+	}
+
+	r := &ApacheRedirect{
+		Line:      d.Line,
+		Directive: d.Name + " " + strings.Join(d.Args, " "),
+	}
+	r.ResponseType = responseType
+
+	return r, nil
+}
+
+// apacheRewriteRule converts a RewriteRule that redirects. Conditions on the
+// host name narrow the sources, joining the hosts of conditions chained with
+// [OR], and conditions on HTTPS limit them to plain http; any other
+// condition cannot be expressed as a rule.
+func apacheRewriteRule(file string, d apacheDirective, conditions []apacheDirective, names []string) (*ApacheRedirect, error) {
+	if len(d.Args) < 2 {
+		return nil, fmt.Errorf("RewriteRule needs a pattern and a substitution")
+	}
+
+	rr := HieraRewriteRule{
+		Pattern: d.Args[0],
+		Target:  d.Args[1],
+	}
+
+	if len(d.Args) > 2 {
+		if err := rr.Flags.parseFlags(d.Args[2]); err != nil {
+			return nil, fmt.Errorf("RewriteRule %w", err)
+		}
+	}
+
+	if rr.Flags.Forbidden || rr.Flags.Gone {
+		return nil, fmt.Errorf("RewriteRule %s does not redirect", rr.Pattern)
+	}
+
+	httpOnly := false
+
+	// Conditions chained with [OR] form a group and every group must match.
+	var hosts, group []string
+	groupHTTP := false
+
+	for i, c := range conditions {
+		if len(c.Args) < 2 || len(c.Args) > 3 {
+			return nil, fmt.Errorf("RewriteCond on line %d needs a test string, a pattern and optional flags", c.Line)
+		}
+
+		or, err := apacheConditionFlags(c)
+		if err != nil {
+			return nil, err
+		}
+
+		test, pattern := strings.ToUpper(c.Args[0]), c.Args[1]
+
+		switch {
+		case test == "%{HTTP_HOST}" && apacheHostCondition.MatchString(pattern):
+			host := apacheHostCondition.FindStringSubmatch(pattern)[1]
+			group = appendHost(group, strings.ToLower(strings.ReplaceAll(host, `\.`, ".")))
+		case test == "%{HTTPS}" && (strings.EqualFold(pattern, "off") || strings.EqualFold(pattern, "!on")):
+			groupHTTP = true
+		case test == "%{SERVER_PORT}" && pattern == "80":
+			groupHTTP = true
+		default:
+			return nil, fmt.Errorf("RewriteCond %s %s on line %d cannot be converted", c.Args[0], pattern, c.Line)
+		}
+
+		if or && i < len(conditions)-1 {
+			continue
+		}
+
+		switch {
+		case len(group) > 0 && groupHTTP:
+			return nil, fmt.Errorf("RewriteCond on line %d joins host and HTTPS conditions with [OR]", c.Line)
+		case groupHTTP:
+			httpOnly = true
+		case hosts == nil:
+			hosts = group
+		default:
+			hosts = intersectHosts(hosts, group)
+			if len(hosts) == 0 {
+				return nil, fmt.Errorf("RewriteCond on line %d requires a different host than an earlier condition", c.Line)
+			}
+		}
+
+		group, groupHTTP = nil, false
+	}
+
+	if hosts != nil {
+		names = hosts
+	}
+
+	r, err := rr.redirect(names, httpOnly)
+	if err != nil || r == nil {
+		return nil, err
+	}
+
+	if rr.Flags.NoCase {
+		log.Warn().Msgf("%s:%d: RewriteRule %s is case insensitive, which needs the host case_insensitive match option", file, d.Line, rr.Pattern)
+	}
+
+	r.Line = d.Line
+	r.Directive = d.Name + " " + strings.Join(d.Args, " ")
+
+	return r, nil
+}
+
+// apacheConditionFlags reads the flags of a RewriteCond and reports whether
+// it is chained to the next condition with [OR]. Host names are matched case
+// insensitively anyway and [NV] only changes the Vary header, so those are
+// accepted; any other flag changes what the condition matches.
+func apacheConditionFlags(c apacheDirective) (or bool, err error) {
+	if len(c.Args) < 3 {
+		return false, nil
+	}
+
+	unsupported := []string{}
+
+	for _, f := range strings.Split(strings.Trim(c.Args[2], "[]"), ",") {
+		switch strings.ToLower(strings.TrimSpace(f)) {
+		case "or", "ornext":
+			or = true
+		case "nc", "nocase", "nv", "novary", "":
+		default:
+			unsupported = append(unsupported, f)
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return false, fmt.Errorf("RewriteCond on line %d has unsupported flags %s", c.Line, strings.Join(unsupported, ","))
+	}
+
+	return or, nil
+}
+
+// appendHost adds a host name unless it is already listed.
+func appendHost(hosts []string, host string) []string {
+	for _, h := range hosts {
+		if h == host {
+			return hosts
+		}
+	}
+
+	return append(hosts, host)
+}
+
+// intersectHosts keeps the hosts of a that are also in b.
+func intersectHosts(a []string, b []string) (hosts []string) {
+	for _, h := range a {
+		for _, o := range b {
+			if h == o {
+				hosts = append(hosts, h)
+				break
+			}
+		}
+	}
+
+	return hosts
+}
+
+// redirect converts the rewrite rule into a redirect for the given host
+// names. Substitutions without a scheme are only accepted for a single host.
+func (rr *HieraRewriteRule) redirect(names []string, httpOnly bool) (*ApacheRedirect, error) {
+	if rr.Target == "-" {
+		return nil, nil
+	}
+
+	absolute := strings.Contains(rr.Target, "://")
+
+	code := rr.Flags.Redirect
+	if code == 0 {
+		if !absolute {
+			return nil, fmt.Errorf("RewriteRule %s is an internal rewrite, not a redirect", rr.Pattern)
+		}
+		code = 302
+	}
+
+	responseType, ok := responseTypeForStatus(code)
+	if !ok {
+		return nil, fmt.Errorf("RewriteRule status %d is not a redirect", code)
+	}
+
+	path, forwardPath, ok := rewritePath(rr.Pattern)
+	if !ok {
+		return nil, fmt.Errorf("RewriteRule pattern %s cannot be converted", rr.Pattern)
+	}
+
+	target := rr.Target
+	if !absolute {
+		if len(names) != 1 {
+			return nil, fmt.Errorf("RewriteRule substitution %s is relative and there is not a single host name", target)
+		}
+		target = "https://" + names[0] + "/" + strings.TrimPrefix(target, "/")
+	}
+
+	// A trailing question mark drops the query string, as QSD does.
+	discard := rr.Flags.QueryStringDiscard || strings.HasSuffix(target, "?")
+	target = strings.TrimSuffix(target, "?")
+
+	target, err := rewriteTarget(target, forwardPath)
+	if err != nil {
+		return nil, fmt.Errorf("RewriteRule %w", err)
+	}
+
+	r := &ApacheRedirect{simpleRedirect: simpleRedirect{
+		ResponseType: responseType,
+		ForwardPath:  forwardPath,
+		TargetURL:    target,
+	}}
+
+	// A substitution with its own query string replaces the request's unless
+	// QSA asks for both.
+	r.ForwardParams = !discard && (!strings.Contains(target, "?") || rr.Flags.QueryStringAppend)
+
+	return r, apacheSources(r, names, path, httpOnly)
+}
+
+func apacheSources(r *ApacheRedirect, names []string, path string, httpOnly bool) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%s has no host name, use a VirtualHost with ServerName or set the host", r.Directive)
+	}
+
+	if path == "/" {
+		path = ""
+	}
+
+	for _, n := range names {
+		source := n + path
+		if httpOnly {
+			source = "http://" + source
+		}
+		r.SourceURLs = append(r.SourceURLs, source)
+	}
+
+	return nil
+}
+
+// parseApache splits the configuration into scopes. The first scope holds
+// everything outside a VirtualHost. Other containers such as Directory and
+// IfModule are read through.
+func parseApache(file string, f io.Reader) ([]apacheScope, error) {
+	scopes := []apacheScope{{}}
+	current := 0
+
+	s := bufio.NewScanner(f)
+
+	line := 0
+	start := 0
+	var b strings.Builder
+
+	for s.Scan() {
+		line++
+
+		text := strings.TrimSpace(s.Text())
+		if b.Len() == 0 {
+			start = line
+		}
+
+		if strings.HasSuffix(text, `\`) {
+			b.WriteString(strings.TrimSuffix(text, `\`))
+			b.WriteString(" ")
+			continue
+		}
+		b.WriteString(text)
+
+		text = strings.TrimSpace(b.String())
+		b.Reset()
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields, err := apacheFields(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+
+		name := fields[0]
+
+		switch {
+		case strings.HasPrefix(strings.ToLower(name), "<virtualhost"):
+			if current != 0 {
+				return nil, fmt.Errorf("line %d: nested VirtualHost", start)
+			}
+			scopes = append(scopes, apacheScope{})
+			current = len(scopes) - 1
+		case strings.EqualFold(name, "</VirtualHost>"):
+			if current == 0 {
+				return nil, fmt.Errorf("line %d: unexpected </VirtualHost>", start)
+			}
+			current = 0
+		case strings.HasPrefix(name, "<"):
+		case strings.EqualFold(name, "ServerName") && len(fields) > 1:
+			scopes[current].Names = append([]string{apacheServerName(fields[1])}, scopes[current].Names...)
+		case strings.EqualFold(name, "ServerAlias"):
+			for _, alias := range fields[1:] {
+				if strings.Contains(alias, "*") || strings.Contains(alias, "?") {
+					log.Warn().Msgf("%s:%d: ServerAlias %s cannot be used as a source URL", file, start, alias)
+					continue
+				}
+				scopes[current].Names = append(scopes[current].Names, apacheServerName(alias))
+			}
+		default:
+			scopes[current].Directives = append(scopes[current].Directives, apacheDirective{
+				Name: name,
+				Args: fields[1:],
+				Line: start,
+			})
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if current != 0 {
+		return nil, fmt.Errorf("unexpected end of file, missing </VirtualHost>")
+	}
+
+	return scopes, nil
+}
+
+// apacheServerName drops the scheme and port a ServerName may carry.
+func apacheServerName(name string) string {
+	if i := strings.Index(name, "://"); i != -1 {
+		name = name[i+3:]
+	}
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		name = name[:i]
+	}
+
+	return strings.ToLower(name)
+}
+
+// apacheFields splits a directive into its name and arguments, honouring
+// double quoted arguments.
+func apacheFields(s string) (fields []string, err error) {
+	var b strings.Builder
+
+	quoted := false
+	inField := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\\' && quoted && i+1 < len(s) && s[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inField {
+				fields = append(fields, b.String())
+				b.Reset()
+				inField = false
+			}
+		default:
+			b.WriteByte(c)
+			inField = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+
+	if inField {
+		fields = append(fields, b.String())
+	}
+
+	return fields, nil
+}
+
+func (rs *ApacheRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file, options.Host)
+
+	return rs.redirects()
+}
+
+func (rs *ApacheRedirects) redirects() Redirects {
+	return configRedirects(*rs)
+}
+
+func (r *ApacheRedirect) Print() {
+	printConfig(apachePrintTemplate, r)
+}
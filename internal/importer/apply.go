@@ -0,0 +1,562 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ActionCreate string = "create"
+	ActionUpdate string = "update"
+	ActionDelete string = "delete"
+)
+
+var (
+	ruleAttributeNames = []string{
+		"source_urls",
+		"target_url",
+		"response_type",
+		"forward_params",
+		"forward_path",
+	}
+
+	hostAttributeNames = []string{
+		"match_options.case_insensitive",
+		"match_options.slash_insensitive",
+		"not_found_action.forward_params",
+		"not_found_action.forward_path",
+		"not_found_action.custom_404_body",
+		"not_found_action.response_code",
+		"not_found_action.response_url",
+		"security.https_upgrade",
+		"security.prevent_foreign_embedding",
+		"security.hsts_include_sub_domains",
+		"security.hsts_max_age",
+		"security.hsts_preload",
+	}
+)
+
+// Changes is the set of operations required to make the account match a spec.
+type Changes struct {
+	Rules []RuleChange
+	Hosts []HostChange
+}
+
+type RuleChange struct {
+	Action     string
+	Rule       easyredir.Rule
+	Attributes []AttributeChange
+}
+
+// HostChange has no ID when the host does not exist yet. EasyRedir creates
+// hosts on demand when a rule references them, so the ID is resolved after
+// the rules have been applied.
+type HostChange struct {
+	Action     string
+	ID         string
+	Name       string
+	Options    YAMLRedirectSourceOptions
+	Attributes []AttributeChange
+}
+
+type AttributeChange struct {
+	Name   string
+	Before string
+	After  string
+}
+
+// Apply makes the changes. Live rules missing from the spec are only deleted
+// when prune is set.
+func (rs *YAMLRedirects) Apply(ctx context.Context, c *easyredir.Client, prune bool) {
+	changes, err := rs.Changes(ctx, c)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	if !prune {
+		changes.keepUnmanaged()
+	}
+
+	changes.Print()
+
+	if err = changes.Apply(ctx, c); err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	return
+}
+
+// Changes compares the spec against the live account. Declared rules are
+// matched to live rules by their source URLs and any live rule that is not
+// claimed by the spec is marked for deletion.
+// An empty spec is refused, as it would delete every rule.
+func (rs *YAMLRedirects) Changes(ctx context.Context, c *easyredir.Client) (changes Changes, err error) {
+	if len(*rs) == 0 {
+		return changes, fmt.Errorf("Changes: the spec has no redirects, refusing to delete every rule")
+	}
+
+	rules, err := c.ListRules(ctx, &easyredir.RulesOptions{})
+	if err != nil {
+		return changes, fmt.Errorf("Changes: unable to list rules: %w", err)
+	}
+
+	owners := make(map[string]int)
+	for i, r := range rules.Data {
+		for _, s := range r.Attributes.SourceURLs {
+			owners[normalizeSource(s)] = i
+		}
+	}
+
+	matched := make(map[int]bool)
+
+	for _, r := range *rs {
+		desired := r.rule()
+
+		idx := -1
+		for _, s := range desired.Data.Attributes.SourceUrls {
+			if i, ok := owners[normalizeSource(s)]; ok && !matched[i] {
+				idx = i
+				break
+			}
+		}
+
+		if idx == -1 {
+			changes.Rules = append(changes.Rules, RuleChange{
+				Action:     ActionCreate,
+				Rule:       desired,
+				Attributes: diffRule(nil, &desired),
+			})
+			continue
+		}
+
+		matched[idx] = true
+
+		live := rules.Rule(idx)
+		desired.Data.ID = live.Data.ID
+
+		if diff := diffRule(&live, &desired); len(diff) > 0 {
+			changes.Rules = append(changes.Rules, RuleChange{
+				Action:     ActionUpdate,
+				Rule:       desired,
+				Attributes: diff,
+			})
+		}
+	}
+
+	for i := range rules.Data {
+		if matched[i] {
+			continue
+		}
+
+		live := rules.Rule(i)
+		changes.Rules = append(changes.Rules, RuleChange{
+			Action:     ActionDelete,
+			Rule:       live,
+			Attributes: diffRule(&live, nil),
+		})
+	}
+
+	hosts, err := c.ListHosts(ctx, &easyredir.HostsOptions{})
+	if err != nil {
+		return changes, fmt.Errorf("Changes: unable to list hosts: %w", err)
+	}
+
+	// Host names are case insensitive.
+	ids := make(map[string]string)
+	for _, h := range hosts.Data {
+		ids[strings.ToLower(h.Attributes.Name)] = h.ID
+	}
+
+	seen := make(map[string]bool)
+
+	for _, r := range *rs {
+		for _, s := range r.Sources {
+			name := strings.ToLower(sourceHost(*s.URL))
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			id, ok := ids[name]
+			if !ok {
+				// A new host gets the defaults for anything left out.
+				options := s.Options.withDefaults()
+
+				changes.Hosts = append(changes.Hosts, HostChange{
+					Action:     ActionCreate,
+					Name:       name,
+					Options:    options,
+					Attributes: diffHost(nil, options),
+				})
+				continue
+			}
+
+			host := easyredir.Host{}
+			host.Data.ID = id
+			if err = c.GetHost(ctx, &host); err != nil {
+				return changes, fmt.Errorf("Changes: unable to get host %s: %w", name, err)
+			}
+
+			if diff := diffHost(&host, s.Options); len(diff) > 0 {
+				changes.Hosts = append(changes.Hosts, HostChange{
+					Action:     ActionUpdate,
+					ID:         id,
+					Name:       name,
+					Options:    s.Options,
+					Attributes: diff,
+				})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// keepUnmanaged drops the deletion of live rules that the spec does not
+// claim, reporting how many are kept.
+func (cs *Changes) keepUnmanaged() {
+	rules := []RuleChange{}
+
+	for _, rc := range cs.Rules {
+		if rc.Action != ActionDelete {
+			rules = append(rules, rc)
+		}
+	}
+
+	if n := len(cs.Rules) - len(rules); n > 0 {
+		log.Warn().Msgf("Keeping %d rules that are not in the spec, use --prune to delete them.", n)
+	}
+
+	cs.Rules = rules
+}
+
+// Apply deletes rules first so that their source URLs are released before
+// updated or created rules claim them.
+func (cs *Changes) Apply(ctx context.Context, c *easyredir.Client) (err error) {
+	for _, action := range []string{ActionDelete, ActionUpdate, ActionCreate} {
+		for _, rc := range cs.Rules {
+			if rc.Action != action {
+				continue
+			}
+
+			rule := rc.Rule
+
+			switch action {
+			case ActionDelete:
+				if _, err = c.RemoveRule(ctx, &rule); err != nil {
+					return fmt.Errorf("Apply: unable to delete rule %s: %w", rule.Data.ID, err)
+				}
+				fmt.Printf("%s: %s\n", text.FgRed.Sprint("DELETED"), rule.Data.ID)
+				fmt.Println()
+			case ActionUpdate:
+				res, err := c.UpdateRule(ctx, &rule)
+				if err != nil {
+					return fmt.Errorf("Apply: unable to update rule %s: %w", rule.Data.ID, err)
+				}
+				res.Print()
+			case ActionCreate:
+				res, err := c.CreateRule(ctx, &rule)
+				if err != nil {
+					return fmt.Errorf("Apply: unable to create rule: %w", err)
+				}
+				res.Print()
+			}
+		}
+	}
+
+	var ids map[string]string
+
+	for _, hc := range cs.Hosts {
+		id := hc.ID
+
+		if id == "" {
+			if ids == nil {
+				hosts, err := c.ListHosts(ctx, &easyredir.HostsOptions{})
+				if err != nil {
+					return fmt.Errorf("Apply: unable to list hosts: %w", err)
+				}
+
+				ids = make(map[string]string)
+				for _, h := range hosts.Data {
+					ids[strings.ToLower(h.Attributes.Name)] = h.ID
+				}
+			}
+
+			if id = ids[strings.ToLower(hc.Name)]; id == "" {
+				log.Warn().Msg(fmt.Sprintf("Host %s was not created by any rule.", hc.Name))
+				continue
+			}
+		}
+
+		host := easyredir.Host{}
+		host.Data.ID = id
+		if err = c.GetHost(ctx, &host); err != nil {
+			return fmt.Errorf("Apply: unable to get host %s: %w", hc.Name, err)
+		}
+
+		applySourceOptions(&host, hc.Options)
+
+		res, err := c.UpdateHost(ctx, &host)
+		if err != nil {
+			return fmt.Errorf("Apply: unable to update host %s: %w", hc.Name, err)
+		}
+
+		res.Print()
+	}
+
+	return nil
+}
+
+func (r *YAMLRedirect) rule() (rule easyredir.Rule) {
+	rule.Data.Attributes.ForwardParams = *r.ForwardParams
+	rule.Data.Attributes.ForwardPath = *r.ForwardPath
+	rule.Data.Attributes.ResponseType = *r.ResponseType
+
+	for _, v := range r.Sources {
+		rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, *v.URL)
+	}
+	rule.Data.Attributes.TargetURL = *r.TargetURL
+
+	return rule
+}
+
+func applySourceOptions(host *easyredir.Host, o YAMLRedirectSourceOptions) {
+	if o.MatchOptions.CaseInsensitive != nil {
+		host.Data.Attributes.MatchOptions.CaseInsensitive = *o.MatchOptions.CaseInsensitive
+	}
+	if o.MatchOptions.SlashInsensitive != nil {
+		host.Data.Attributes.MatchOptions.SlashInsensitive = *o.MatchOptions.SlashInsensitive
+	}
+
+	if o.NotFoundAction.ForwardParams != nil {
+		host.Data.Attributes.NotFoundAction.ForwardParams = *o.NotFoundAction.ForwardParams
+	}
+	if o.NotFoundAction.ForwardPath != nil {
+		host.Data.Attributes.NotFoundAction.ForwardPath = *o.NotFoundAction.ForwardPath
+	}
+	if o.NotFoundAction.Custom404Body != nil {
+		host.Data.Attributes.NotFoundAction.Custom404Body = *o.NotFoundAction.Custom404Body
+	}
+	if o.NotFoundAction.ResponseCode != nil {
+		host.Data.Attributes.NotFoundAction.ResponseCode = *o.NotFoundAction.ResponseCode
+	}
+	if o.NotFoundAction.ResponseURL != nil {
+		host.Data.Attributes.NotFoundAction.ResponseURL = *o.NotFoundAction.ResponseURL
+	}
+
+	if o.Security.HTTPSUpgrade != nil {
+		host.Data.Attributes.Security.HTTPSUpgrade = *o.Security.HTTPSUpgrade
+	}
+	if o.Security.PreventForeignEmbedding != nil {
+		host.Data.Attributes.Security.PreventForeignEmbedding = *o.Security.PreventForeignEmbedding
+	}
+	if o.Security.HSTSIncludeSubDomains != nil {
+		host.Data.Attributes.Security.HstsIncludeSubDomains = *o.Security.HSTSIncludeSubDomains
+	}
+	if o.Security.HSTSMaxAge != nil {
+		host.Data.Attributes.Security.HstsMaxAge = *o.Security.HSTSMaxAge
+	}
+	if o.Security.HSTSPreload != nil {
+		host.Data.Attributes.Security.HstsPreload = *o.Security.HSTSPreload
+	}
+
+	return
+}
+
+func ruleAttributes(r *easyredir.Rule) []string {
+	sources := []string{}
+	for _, s := range r.Data.Attributes.SourceUrls {
+		sources = append(sources, normalizeSource(s))
+	}
+	sort.Strings(sources)
+
+	return []string{
+		strings.Join(sources, ", "),
+		r.Data.Attributes.TargetURL,
+		r.Data.Attributes.ResponseType,
+		strconv.FormatBool(r.Data.Attributes.ForwardParams),
+		strconv.FormatBool(r.Data.Attributes.ForwardPath),
+	}
+}
+
+// diffRule lists every attribute when either side is nil, otherwise only the
+// attributes that differ.
+func diffRule(before, after *easyredir.Rule) (diff []AttributeChange) {
+	var b, a []string
+
+	if before != nil {
+		b = ruleAttributes(before)
+	}
+	if after != nil {
+		a = ruleAttributes(after)
+	}
+
+	for i, name := range ruleAttributeNames {
+		ac := AttributeChange{Name: name}
+		if b != nil {
+			ac.Before = b[i]
+		}
+		if a != nil {
+			ac.After = a[i]
+		}
+
+		if b != nil && a != nil && ac.Before == ac.After {
+			continue
+		}
+
+		diff = append(diff, ac)
+	}
+
+	return diff
+}
+
+func hostAttributes(h *easyredir.Host) []string {
+	a := h.Data.Attributes
+
+	custom404Body := a.NotFoundAction.Custom404Body
+	if custom404Body == "" && a.NotFoundAction.Custom404BodyPresent {
+		custom404Body = "(present)"
+	}
+
+	return []string{
+		formatValue(a.MatchOptions.CaseInsensitive, "false"),
+		formatValue(a.MatchOptions.SlashInsensitive, "false"),
+		formatValue(a.NotFoundAction.ForwardParams, "false"),
+		formatValue(a.NotFoundAction.ForwardPath, "false"),
+		custom404Body,
+		strconv.Itoa(a.NotFoundAction.ResponseCode),
+		formatValue(a.NotFoundAction.ResponseURL, ""),
+		formatValue(a.Security.HTTPSUpgrade, "false"),
+		formatValue(a.Security.PreventForeignEmbedding, "false"),
+		formatValue(a.Security.HstsIncludeSubDomains, "false"),
+		formatValue(a.Security.HstsMaxAge, "0"),
+		formatValue(a.Security.HstsPreload, "false"),
+	}
+}
+
+// sourceOptionAttributes returns nil for options the spec leaves undeclared.
+// A HSTS max age of -1 means the header is not added, so it is not compared.
+func sourceOptionAttributes(o YAMLRedirectSourceOptions) []*string {
+	str := func(s string) *string { return &s }
+
+	attributes := make([]*string, len(hostAttributeNames))
+
+	if v := o.MatchOptions.CaseInsensitive; v != nil {
+		attributes[0] = str(strconv.FormatBool(*v))
+	}
+	if v := o.MatchOptions.SlashInsensitive; v != nil {
+		attributes[1] = str(strconv.FormatBool(*v))
+	}
+	if v := o.NotFoundAction.ForwardParams; v != nil {
+		attributes[2] = str(strconv.FormatBool(*v))
+	}
+	if v := o.NotFoundAction.ForwardPath; v != nil {
+		attributes[3] = str(strconv.FormatBool(*v))
+	}
+	if v := o.NotFoundAction.Custom404Body; v != nil {
+		attributes[4] = str(*v)
+	}
+	if v := o.NotFoundAction.ResponseCode; v != nil {
+		attributes[5] = str(strconv.Itoa(*v))
+	}
+	if v := o.NotFoundAction.ResponseURL; v != nil {
+		attributes[6] = str(*v)
+	}
+	if v := o.Security.HTTPSUpgrade; v != nil {
+		attributes[7] = str(strconv.FormatBool(*v))
+	}
+	if v := o.Security.PreventForeignEmbedding; v != nil {
+		attributes[8] = str(strconv.FormatBool(*v))
+	}
+	if v := o.Security.HSTSIncludeSubDomains; v != nil {
+		attributes[9] = str(strconv.FormatBool(*v))
+	}
+	if v := o.Security.HSTSMaxAge; v != nil && *v != -1 {
+		attributes[10] = str(strconv.Itoa(*v))
+	}
+	if v := o.Security.HSTSPreload; v != nil {
+		attributes[11] = str(strconv.FormatBool(*v))
+	}
+
+	return attributes
+}
+
+// diffHost lists every declared option when the host does not exist yet. The
+// API never returns the custom 404 body, only whether one is present, so a
+// declared body is considered unchanged whenever the host already has one.
+func diffHost(host *easyredir.Host, o YAMLRedirectSourceOptions) (diff []AttributeChange) {
+	var b []string
+
+	if host != nil {
+		b = hostAttributes(host)
+	}
+	a := sourceOptionAttributes(o)
+
+	for i, name := range hostAttributeNames {
+		if a[i] == nil {
+			continue
+		}
+
+		ac := AttributeChange{Name: name, After: *a[i]}
+
+		if b != nil {
+			ac.Before = b[i]
+
+			if ac.Before == ac.After {
+				continue
+			}
+			if name == "not_found_action.custom_404_body" && ac.Before != "" && ac.After != "" {
+				continue
+			}
+		}
+
+		diff = append(diff, ac)
+	}
+
+	return diff
+}
+
+func formatValue(v interface{}, def string) string {
+	switch t := v.(type) {
+	case nil:
+		return def
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// normalizeSource strips the trailing slash the API appends to source URLs.
+func normalizeSource(s string) string {
+	return strings.TrimRight(s, "/")
+}
+
+func sourceHost(s string) string {
+	u, err := sourceURL(s)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
+
+// sourceURL parses a source URL, which may leave out the scheme.
+func sourceURL(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	return url.Parse(s)
+}
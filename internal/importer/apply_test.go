@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func TestLoadSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want:    1,
+		},
+		{
+			name:    "syntax error",
+			content: "- sources: [\n",
+			wantErr: "Load: ",
+		},
+		{
+			name:    "unknown field",
+			content: "- sources:\n  - url: old.com\n  target: https://new.com\n",
+			wantErr: "Load: ",
+		},
+		{
+			name:    "missing target",
+			content: "- sources:\n  - url: old.com\n",
+			wantErr: "Validate: redirect 1: missing target_url",
+		},
+		{
+			name:    "missing source url",
+			content: "- sources:\n  - url: old.com\n  target_url: https://new.com\n- sources:\n  - options: {}\n  target_url: https://new.com\n",
+			wantErr: "Validate: redirect 2: source 1: missing url",
+		},
+		{
+			name:    "missing sources",
+			content: "- target_url: https://new.com\n",
+			wantErr: "Validate: redirect 1: missing sources",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := loadSpec(writeFile(t, "spec.yaml", tt.content))
+
+			if tt.wantErr != "" {
+				if err == nil || !hasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("loadSpec: %v", err)
+			}
+
+			if len(rs) != tt.want {
+				t.Errorf("redirects = %d, want %d", len(rs), tt.want)
+			}
+
+			if rs[0].ResponseType == nil || *rs[0].ResponseType != defaultResponseType {
+				t.Errorf("defaults not applied")
+			}
+		})
+	}
+
+	if _, err := loadSpec("/nonexistent/spec.yaml"); err == nil {
+		t.Error("loadSpec: want an error for a missing file")
+	}
+}
+
+func hasPrefix(s string, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		live    [][2]string
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "create",
+			spec: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want: map[string]string{"old.com": ActionCreate},
+		},
+		{
+			name: "update",
+			live: [][2]string{{"old.com", "https://before.com"}},
+			spec: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want: map[string]string{"old.com": ActionUpdate},
+		},
+		{
+			name: "unchanged",
+			live: [][2]string{{"old.com", "https://new.com"}},
+			spec: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want: map[string]string{},
+		},
+		{
+			name: "delete",
+			live: [][2]string{{"old.com", "https://new.com"}, {"stale.com", "https://new.com"}},
+			spec: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want: map[string]string{"stale.com": ActionDelete},
+		},
+		{
+			name:    "empty spec",
+			live:    [][2]string{{"old.com", "https://new.com"}},
+			spec:    "[]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			for _, r := range tt.live {
+				s.MustAddRule(t, easyredirtest.NewRule(r[1], r[0]))
+			}
+
+			rs := YAMLRedirects{}
+			if err := rs.Load(writeFile(t, "spec.yaml", tt.spec)); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			rs.Defaults()
+
+			changes, err := rs.Changes(testContext(t), c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Changes: want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Changes: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, rc := range changes.Rules {
+				got[exportSource(rc.Rule.Data.Attributes.SourceUrls[0])] = rc.Action
+			}
+
+			if !equalTargets(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	spec := `
+- sources:
+  - url: old.com
+  target_url: https://updated.com
+- sources:
+  - url: added.com
+    options:
+      security:
+        https_upgrade: true
+  target_url: https://added.com
+`
+
+	tests := []struct {
+		name  string
+		prune bool
+		want  map[string]string
+	}{
+		{
+			name: "keep unmanaged",
+			want: map[string]string{
+				"old.com":   "https://updated.com",
+				"added.com": "https://added.com",
+				"stale.com": "https://stale.com",
+			},
+		},
+		{
+			name:  "prune",
+			prune: true,
+			want: map[string]string{
+				"old.com":   "https://updated.com",
+				"added.com": "https://added.com",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			s.MustAddRule(t, easyredirtest.NewRule("https://before.com", "old.com"))
+			s.MustAddRule(t, easyredirtest.NewRule("https://stale.com", "stale.com"))
+
+			rs, err := loadSpec(writeFile(t, "spec.yaml", spec))
+			if err != nil {
+				t.Fatalf("loadSpec: %v", err)
+			}
+
+			rs.Apply(testContext(t), c, tt.prune)
+
+			if got := targets(s); !equalTargets(got, tt.want) {
+				t.Errorf("rules = %v, want %v", got, tt.want)
+			}
+
+			for _, h := range s.Hosts() {
+				if h.Data.Attributes.Name != "added.com" {
+					continue
+				}
+				if got := h.Data.Attributes.Security.HTTPSUpgrade; got != true {
+					t.Errorf("added.com https_upgrade = %v, want true", got)
+				}
+			}
+
+			// A second run finds nothing left to do.
+			changes, err := rs.Changes(testContext(t), c)
+			if err != nil {
+				t.Fatalf("Changes: %v", err)
+			}
+			if !tt.prune {
+				changes.keepUnmanaged()
+			}
+			if len(changes.Rules) > 0 || len(changes.Hosts) > 0 {
+				t.Errorf("changes after apply = %+v, want none", changes)
+			}
+		})
+	}
+}
+
+func TestChangesHosts(t *testing.T) {
+	tests := []struct {
+		name         string
+		host         string
+		httpsUpgrade bool
+		spec         string
+		want         []string
+	}{
+		{
+			name: "host name in another case",
+			host: "Old.com",
+			spec: "- sources:\n  - url: old.com\n    options:\n      match_options:\n        case_insensitive: true\n  target_url: https://new.com\n",
+			want: []string{"update match_options.case_insensitive"},
+		},
+		{
+			name:         "undeclared options",
+			host:         "old.com",
+			httpsUpgrade: true,
+			spec:         "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+		},
+		{
+			name: "new host",
+			host: "other.com",
+			spec: "- sources:\n  - url: old.com\n    options:\n      security:\n        https_upgrade: true\n  target_url: https://new.com\n",
+			want: []string{"create match_options.case_insensitive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+
+			host := s.AddHost(tt.host)
+			host.Data.Attributes.Security.HTTPSUpgrade = tt.httpsUpgrade
+			if _, err := c.UpdateHost(testContext(t), &host); err != nil {
+				t.Fatalf("UpdateHost: %v", err)
+			}
+
+			rs, err := loadSpec(writeFile(t, "spec.yaml", tt.spec))
+			if err != nil {
+				t.Fatalf("loadSpec: %v", err)
+			}
+
+			changes, err := rs.Changes(testContext(t), c)
+			if err != nil {
+				t.Fatalf("Changes: %v", err)
+			}
+
+			got := []string{}
+			for _, hc := range changes.Hosts {
+				got = append(got, hc.Action+" "+hc.Attributes[0].Name)
+			}
+
+			if !reflect.DeepEqual(got, append([]string{}, tt.want...)) {
+				t.Errorf("host changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed cloudflare_print.tmpl
+var cloudflarePrintTemplate string
+
+func init() {
+	Register(Format{
+		Name: "cloudflare",
+		New:  func() Importer { return &CloudflareRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".csv", ".json") && cloudflareContent(content)
+		},
+	})
+
+	RegisterExport(ExportFormat{
+		Name: "cloudflare",
+		Export: ruleExporter(func(rs []easyredir.Rule, w io.Writer) error {
+			return exportCloudflare(rs, w, false)
+		}),
+	})
+
+	RegisterExport(ExportFormat{
+		Name: "cloudflare-csv",
+		Export: ruleExporter(func(rs []easyredir.Rule, w io.Writer) error {
+			return exportCloudflare(rs, w, true)
+		}),
+	})
+}
+
+type CloudflareRedirects []CloudflareRedirect
+
+type CloudflareRedirect struct {
+	simpleRedirect
+}
+
+// cloudflareItem is a Bulk Redirect list item. The API and the dashboard
+// export wrap it in a "redirect" object; both forms are read.
+type cloudflareItem struct {
+	SourceURL           string `json:"source_url"`
+	TargetURL           string `json:"target_url"`
+	StatusCode          int    `json:"status_code,omitempty"`
+	PreserveQueryString *bool  `json:"preserve_query_string,omitempty"`
+	IncludeSubdomains   *bool  `json:"include_subdomains,omitempty"`
+	SubpathMatching     *bool  `json:"subpath_matching,omitempty"`
+	PreservePathSuffix  *bool  `json:"preserve_path_suffix,omitempty"`
+}
+
+type cloudflareListItem struct {
+	Redirect *cloudflareItem `json:"redirect,omitempty"`
+	cloudflareItem
+}
+
+type cloudflareExportItem struct {
+	Redirect cloudflareItem `json:"redirect"`
+}
+
+var cloudflareColumns = []string{"source_url", "target_url", "status_code", "preserve_query_string", "include_subdomains", "subpath_matching", "preserve_path_suffix"}
+
+// Load reads a Bulk Redirect list as JSON or CSV, whichever the file holds.
+// Items that share a target and options become a single rule.
+func (rs *CloudflareRedirects) Load(file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	var items []cloudflareItem
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		items, err = cloudflareJSON(trimmed)
+	} else {
+		items, err = cloudflareCSV(content)
+	}
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
+	}
+
+	groups := make(map[string]int)
+
+	for i, item := range items {
+		r, err := item.redirect()
+		if err != nil {
+			log.Warn().Msgf("%s: item %d: %s", file, i+1, err)
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s|%t|%t", r.TargetURL, r.ResponseType, r.ForwardParams, r.ForwardPath)
+		if j, ok := groups[key]; ok {
+			(*rs)[j].SourceURLs = append((*rs)[j].SourceURLs, r.SourceURLs...)
+			continue
+		}
+
+		groups[key] = len(*rs)
+		*rs = append(*rs, *r)
+	}
+
+	return
+}
+
+func cloudflareJSON(content []byte) (items []cloudflareItem, err error) {
+	list := []cloudflareListItem{}
+
+	// API responses hold the items in result.
+	if content[0] == '{' {
+		response := struct {
+			Result []cloudflareListItem `json:"result"`
+		}{}
+		if err = json.Unmarshal(content, &response); err != nil {
+			return nil, fmt.Errorf("unable to decode json: %w", err)
+		}
+		list = response.Result
+	} else if err = json.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("unable to decode json: %w", err)
+	}
+
+	for _, v := range list {
+		if v.Redirect != nil {
+			items = append(items, *v.Redirect)
+			continue
+		}
+		items = append(items, v.cloudflareItem)
+	}
+
+	return items, nil
+}
+
+func cloudflareCSV(content []byte) (items []cloudflareItem, err error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	if _, ok := columns["source_url"]; !ok {
+		return nil, fmt.Errorf("missing source_url column")
+	}
+	if _, ok := columns["target_url"]; !ok {
+		return nil, fmt.Errorf("missing target_url column")
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+
+		get := func(c string) string {
+			i, ok := columns[c]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		item := cloudflareItem{
+			SourceURL: get("source_url"),
+			TargetURL: get("target_url"),
+		}
+
+		if v := get("status_code"); v != "" {
+			if item.StatusCode, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid status_code %s", line, v)
+			}
+		}
+
+		for c, p := range map[string]**bool{
+			"preserve_query_string": &item.PreserveQueryString,
+			"include_subdomains":    &item.IncludeSubdomains,
+			"subpath_matching":      &item.SubpathMatching,
+			"preserve_path_suffix":  &item.PreservePathSuffix,
+		} {
+			if *p, err = csvBool(get(c)); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", line, c, err)
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// redirect converts the item using the Cloudflare defaults for missing
+// options. Subdomain matching has no equivalent and is reported.
+func (item *cloudflareItem) redirect() (*CloudflareRedirect, error) {
+	if item.SourceURL == "" || item.TargetURL == "" {
+		return nil, fmt.Errorf("source_url and target_url are required")
+	}
+
+	status := item.StatusCode
+	if status == 0 {
+		status = 301
+	}
+
+	responseType, ok := responseTypeForStatus(status)
+	if !ok {
+		return nil, fmt.Errorf("status_code %d has no equivalent response type", status)
+	}
+
+	value := func(b *bool, def bool) bool {
+		if b == nil {
+			return def
+		}
+		return *b
+	}
+
+	subpath := value(item.SubpathMatching, false)
+	suffix := value(item.PreservePathSuffix, true)
+
+	if subpath && !suffix {
+		return nil, fmt.Errorf("subpath_matching without preserve_path_suffix for %s has no equivalent", item.SourceURL)
+	}
+
+	if value(item.IncludeSubdomains, false) {
+		log.Warn().Msgf("include_subdomains for %s has no equivalent, only the host itself is redirected", item.SourceURL)
+	}
+
+	return &CloudflareRedirect{simpleRedirect: simpleRedirect{
+		SourceURLs:    []string{item.SourceURL},
+		TargetURL:     item.TargetURL,
+		ForwardParams: value(item.PreserveQueryString, false),
+		ForwardPath:   subpath,
+		ResponseType:  responseType,
+	}}, nil
+}
+
+// exportCloudflare writes rules as a Bulk Redirect list, one item per source
+// URL, in JSON or CSV. Sources with a query string cannot be matched by
+// Cloudflare and are reported and left out.
+func exportCloudflare(rules []easyredir.Rule, w io.Writer, asCSV bool) error {
+	items := []cloudflareExportItem{}
+
+	for _, rule := range rules {
+		a := rule.Data.Attributes
+
+		for _, s := range a.SourceUrls {
+			if strings.Contains(s, "?") {
+				log.Warn().Msgf("rule %s source %s matches a query string, which has no equivalent", rule.Data.ID, s)
+				continue
+			}
+
+			item := cloudflareItem{
+				SourceURL:           s,
+				TargetURL:           a.TargetURL,
+				StatusCode:          statusForResponseType(a.ResponseType),
+				PreserveQueryString: boolPtr(a.ForwardParams),
+				IncludeSubdomains:   boolPtr(false),
+				SubpathMatching:     boolPtr(a.ForwardPath),
+				PreservePathSuffix:  boolPtr(a.ForwardPath),
+			}
+
+			items = append(items, cloudflareExportItem{Redirect: item})
+		}
+	}
+
+	if !asCSV {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+
+		if err := enc.Encode(items); err != nil {
+			return fmt.Errorf("exportCloudflare: unable to encode json: %w", err)
+		}
+
+		return nil
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(cloudflareColumns)
+
+	for _, v := range items {
+		r := v.Redirect
+		cw.Write([]string{
+			r.SourceURL,
+			r.TargetURL,
+			strconv.Itoa(r.StatusCode),
+			strconv.FormatBool(*r.PreserveQueryString),
+			strconv.FormatBool(*r.IncludeSubdomains),
+			strconv.FormatBool(*r.SubpathMatching),
+			strconv.FormatBool(*r.PreservePathSuffix),
+		})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("exportCloudflare: unable to write csv: %w", err)
+	}
+
+	return nil
+}
+
+func (rs *CloudflareRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file)
+
+	return rs.redirects()
+}
+
+func (rs *CloudflareRedirects) redirects() Redirects {
+	return configRedirects(*rs)
+}
+
+func (r *CloudflareRedirect) Print() {
+	printConfig(cloudflarePrintTemplate, r)
+}
+
+// cloudflareContent reports whether a CSV header or JSON list uses the Bulk
+// Redirect field names.
+func cloudflareContent(content []byte) bool {
+	if !bytes.Contains(content, []byte("source_url")) || !bytes.Contains(content, []byte("target_url")) {
+		return false
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return true
+	}
+
+	for _, c := range cloudflareColumns[3:] {
+		if bytes.Contains(content, []byte(c)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	Register(Format{
+		Name: "cloudflare-pagerules",
+		New: func() Importer {
+			return ImporterFunc(func(file string, options *Options) Redirects {
+				rs := CloudflareRedirects{}
+				rs.LoadPageRules(file)
+
+				return rs.redirects()
+			})
+		},
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".json") && bytes.Contains(content, []byte(`"forwarding_url"`))
+		},
+	})
+
+	RegisterExport(ExportFormat{Name: "cloudflare-pagerules", Export: ruleExporter(exportCloudflarePageRules)})
+}
+
+// cloudflarePageRule is a Page Rule as returned by the API. Only rules with a
+// single URL target and a forwarding URL action can be converted.
+type cloudflarePageRule struct {
+	ID      string                     `json:"id,omitempty"`
+	Targets []cloudflarePageRuleTarget `json:"targets"`
+	Actions []cloudflarePageRuleAction `json:"actions"`
+	Status  string                     `json:"status,omitempty"`
+}
+
+type cloudflarePageRuleTarget struct {
+	Target     string `json:"target"`
+	Constraint struct {
+		Operator string `json:"operator"`
+		Value    string `json:"value"`
+	} `json:"constraint"`
+}
+
+type cloudflarePageRuleAction struct {
+	ID    string          `json:"id"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type cloudflareForwardingURL struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// LoadPageRules reads Page Rules from an API response or a list of rules.
+// Forwarding URLs keep the query string, and a trailing wildcard forwarded
+// as $1 forwards the path.
+func (rs *CloudflareRedirects) LoadPageRules(file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	rules := []cloudflarePageRule{}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		response := struct {
+			Result []cloudflarePageRule `json:"result"`
+		}{}
+		err = json.Unmarshal(trimmed, &response)
+		rules = response.Result
+	} else {
+		err = json.Unmarshal(trimmed, &rules)
+	}
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: unable to decode json: %w", file, err)).Msg("")
+		return
+	}
+
+	for i, pr := range rules {
+		r, err := pr.redirect()
+		if err != nil {
+			log.Warn().Msgf("%s: page rule %d: %s", file, i+1, err)
+			continue
+		}
+
+		*rs = append(*rs, *r)
+	}
+
+	return
+}
+
+func (pr *cloudflarePageRule) redirect() (*CloudflareRedirect, error) {
+	if pr.Status != "" && pr.Status != "active" {
+		return nil, fmt.Errorf("status is %s", pr.Status)
+	}
+
+	if len(pr.Targets) != 1 || pr.Targets[0].Target != "url" || pr.Targets[0].Constraint.Operator != "matches" {
+		return nil, fmt.Errorf("only a single url matches target can be converted")
+	}
+
+	if len(pr.Actions) != 1 || pr.Actions[0].ID != "forwarding_url" {
+		return nil, fmt.Errorf("only a forwarding_url action can be converted")
+	}
+
+	fw := cloudflareForwardingURL{}
+	if err := json.Unmarshal(pr.Actions[0].Value, &fw); err != nil {
+		return nil, fmt.Errorf("invalid forwarding_url: %w", err)
+	}
+
+	responseType, ok := responseTypeForStatus(fw.StatusCode)
+	if !ok {
+		return nil, fmt.Errorf("status_code %d has no equivalent response type", fw.StatusCode)
+	}
+
+	pattern := pr.Targets[0].Constraint.Value
+	target := fw.URL
+
+	forwardPath := false
+	if strings.HasSuffix(pattern, "*") && strings.HasSuffix(target, "$1") {
+		pattern = strings.TrimSuffix(pattern, "*")
+		target = strings.TrimSuffix(target, "$1")
+		forwardPath = true
+	}
+
+	if strings.Contains(pattern, "*") || strings.Contains(target, "$") {
+		return nil, fmt.Errorf("wildcards in %s %s cannot be converted", pr.Targets[0].Constraint.Value, fw.URL)
+	}
+
+	return &CloudflareRedirect{simpleRedirect: simpleRedirect{
+		SourceURLs:    []string{pattern},
+		TargetURL:     target,
+		ForwardParams: true,
+		ForwardPath:   forwardPath,
+		ResponseType:  responseType,
+	}}, nil
+}
+
+// exportCloudflarePageRules writes rules as Page Rules ready to create
+// through the API, one per source URL. Forwarding URLs only redirect with 301
+// or 302 and always keep the query string; rules that need otherwise are
+// reported, and left out when the status cannot be kept.
+func exportCloudflarePageRules(rules []easyredir.Rule, w io.Writer) error {
+	pageRules := []cloudflarePageRule{}
+
+	for _, rule := range rules {
+		a := rule.Data.Attributes
+
+		status := statusForResponseType(a.ResponseType)
+		if status != 301 && status != 302 {
+			log.Warn().Msgf("rule %s responds with %d, which a forwarding URL cannot", rule.Data.ID, status)
+			continue
+		}
+
+		if !a.ForwardParams {
+			log.Warn().Msgf("rule %s drops the query string, which a forwarding URL always keeps", rule.Data.ID)
+		}
+
+		for _, s := range a.SourceUrls {
+			pattern := exportSource(s)
+			target := a.TargetURL
+			if a.ForwardPath {
+				pattern = strings.TrimRight(pattern, "/") + "/*"
+				target = strings.TrimRight(target, "/") + "/$1"
+			}
+
+			value, err := json.Marshal(cloudflareForwardingURL{URL: target, StatusCode: status})
+			if err != nil {
+				return fmt.Errorf("exportCloudflarePageRules: %w", err)
+			}
+
+			pr := cloudflarePageRule{
+				Targets: []cloudflarePageRuleTarget{{Target: "url"}},
+				Actions: []cloudflarePageRuleAction{{ID: "forwarding_url", Value: value}},
+				Status:  "active",
+			}
+			pr.Targets[0].Constraint.Operator = "matches"
+			pr.Targets[0].Constraint.Value = pattern
+
+			pageRules = append(pageRules, pr)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(pageRules); err != nil {
+		return fmt.Errorf("exportCloudflarePageRules: unable to encode json: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ConflictAbort string = "abort"
+	ConflictSkip  string = "skip"
+	ConflictMerge string = "merge"
+)
+
+var ConflictModes = []string{ConflictAbort, ConflictSkip, ConflictMerge}
+
+// CheckConflictMode returns an error listing the valid modes when mode is
+// not one of them.
+func CheckConflictMode(mode string) error {
+	for _, m := range ConflictModes {
+		if m == mode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown conflict mode %q, use one of %s", mode, strings.Join(ConflictModes, ", "))
+}
+
+// ActionSkip marks a rule left out because of a conflict.
+const ActionSkip string = "skip"
+
+// conflictDecision is what to submit for one incoming rule. Merged rules are
+// updates of the owning rule.
+type conflictDecision struct {
+	Action    string
+	Rule      easyredir.Rule
+	Conflicts easyredir.Conflicts
+}
+
+// checkConflicts compares every incoming rule with the live rules on the same
+// hosts and with the rules earlier in the import, before anything is
+// submitted. Without a client only the import itself is checked. In abort
+// mode any conflict fails the whole import.
+func checkConflicts(ctx context.Context, c *easyredir.Client, rules []easyredir.Rule, mode string) ([]conflictDecision, error) {
+	if mode == "" {
+		mode = ConflictAbort
+	}
+
+	if err := CheckConflictMode(mode); err != nil {
+		return nil, fmt.Errorf("checkConflicts: %w", err)
+	}
+
+	s := &easyredir.Simulator{}
+
+	if c != nil {
+		sources := []string{}
+		for _, r := range rules {
+			sources = append(sources, r.Data.Attributes.SourceUrls...)
+		}
+
+		var err error
+
+		s, err = c.Simulator(ctx, easyredir.SourceHosts(sources)...)
+		if err != nil {
+			return nil, fmt.Errorf("checkConflicts: %w", err)
+		}
+	}
+
+	decisions := []conflictDecision{}
+	all := easyredir.Conflicts{}
+
+	for _, r := range rules {
+		d := conflictDecision{Action: ActionCreate, Rule: r}
+		d.Conflicts = s.Conflicts(&r)
+
+		if len(d.Conflicts) > 0 {
+			all = append(all, d.Conflicts...)
+
+			switch mode {
+			case ConflictSkip:
+				d.Action = ActionSkip
+			case ConflictMerge:
+				owners := d.Conflicts.Owners()
+				owner, ok := s.Rule(owners[0])
+
+				// Only a single live rule can absorb the incoming one.
+				if len(owners) != 1 || owners[0] == "" || !ok {
+					log.Warn().Msgf("cannot merge rule for %s into %s, skipping", strings.Join(r.Data.Attributes.SourceUrls, ", "), strings.Join(owners, ", "))
+					d.Action = ActionSkip
+					break
+				}
+
+				d.Action = ActionUpdate
+				d.Rule = s.Merge(*owner, &r)
+				*owner = d.Rule
+			}
+		}
+
+		if d.Action == ActionCreate {
+			s.Rules = append(s.Rules, r)
+		}
+
+		decisions = append(decisions, d)
+	}
+
+	if len(all) == 0 {
+		return decisions, nil
+	}
+
+	all.Print()
+	fmt.Fprintln(os.Stderr)
+
+	if mode == ConflictAbort {
+		return nil, fmt.Errorf("checkConflicts: %w", &easyredir.ConflictError{Conflicts: all})
+	}
+
+	return decisions, nil
+}
+
+// submit creates the rule or, when it was merged, updates its owner.
+func (d *conflictDecision) submit(ctx context.Context, c *easyredir.Client) (*easyredir.Rule, error) {
+	if d.Action == ActionUpdate {
+		return c.UpdateRule(ctx, &d.Rule)
+	}
+
+	return c.CreateRule(ctx, &d.Rule)
+}
@@ -0,0 +1,336 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed csv_print.tmpl
+var csvPrintTemplate string
+
+func init() {
+	Register(Format{
+		Name: "csv",
+		New:  func() Importer { return &CSVRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".csv", ".tsv") && !cloudflareContent(content)
+		},
+	})
+
+	RegisterExport(ExportFormat{Name: "csv", Export: exportCSV})
+}
+
+const (
+	csvColumnID            string = "id"
+	csvColumnSourceURLs    string = "source"
+	csvColumnTargetURL     string = "target"
+	csvColumnResponseType  string = "response_type"
+	csvColumnForwardParams string = "forward_params"
+	csvColumnForwardPath   string = "forward_path"
+)
+
+// csvHeaders maps normalised header names to columns. Headers are compared
+// in lower case with spaces, dashes and underscores removed, so the output
+// of "get rules -o csv" and "export --format csv" reads back unchanged.
+var csvHeaders = map[string]string{
+	"id":                 csvColumnID,
+	"rule":               csvColumnID,
+	"ruleid":             csvColumnID,
+	"source":             csvColumnSourceURLs,
+	"sources":            csvColumnSourceURLs,
+	"sourceurl":          csvColumnSourceURLs,
+	"sourceurls":         csvColumnSourceURLs,
+	"from":               csvColumnSourceURLs,
+	"old":                csvColumnSourceURLs,
+	"oldurl":             csvColumnSourceURLs,
+	"target":             csvColumnTargetURL,
+	"targeturl":          csvColumnTargetURL,
+	"to":                 csvColumnTargetURL,
+	"destination":        csvColumnTargetURL,
+	"new":                csvColumnTargetURL,
+	"newurl":             csvColumnTargetURL,
+	"responsetype":       csvColumnResponseType,
+	"type":               csvColumnResponseType,
+	"status":             csvColumnResponseType,
+	"statuscode":         csvColumnResponseType,
+	"code":               csvColumnResponseType,
+	"forwardparams":      csvColumnForwardParams,
+	"forwardquery":       csvColumnForwardParams,
+	"forwardquerystring": csvColumnForwardParams,
+	"forwardpath":        csvColumnForwardPath,
+}
+
+type CSVRedirects []CSVRedirect
+
+type CSVRedirect struct {
+	ID            string
+	Lines         []int
+	SourceURLs    []string
+	TargetURL     string
+	ForwardParams *bool
+	ForwardPath   *bool
+	ResponseType  *string
+}
+
+// Load reads one redirect per row. Rows that share a target URL and options
+// are grouped into a single rule with every row's sources; a rule ID column
+// keeps rows of different rules apart.
+func (rs *CSVRedirects) Load(file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comma = csvDelimiter(content)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: unable to read header: %w", file, err)).Msg("")
+		return
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		name := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(h)))
+		if c, ok := csvHeaders[name]; ok {
+			if _, dup := columns[c]; !dup {
+				columns[c] = i
+			}
+			continue
+		}
+		if name != "sourcehosts" {
+			log.Warn().Msgf("%s: ignoring column %q", file, h)
+		}
+	}
+
+	for _, c := range []string{csvColumnSourceURLs, csvColumnTargetURL} {
+		if _, ok := columns[c]; !ok {
+			log.Error().Err(fmt.Errorf("%s: missing %s column", file, c)).Msg("")
+			return
+		}
+	}
+
+	groups := make(map[string]int)
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			log.Warn().Msgf("%s: %s", file, err)
+			continue
+		}
+
+		line, _ := r.FieldPos(0)
+
+		get := func(c string) string {
+			i, ok := columns[c]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		sources := strings.FieldsFunc(get(csvColumnSourceURLs), func(c rune) bool {
+			return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == ';' || c == '|' || c == ','
+		})
+
+		rd := CSVRedirect{
+			ID:         get(csvColumnID),
+			Lines:      []int{line},
+			SourceURLs: sources,
+			TargetURL:  get(csvColumnTargetURL),
+		}
+
+		if len(rd.SourceURLs) == 0 && rd.TargetURL == "" {
+			continue
+		}
+
+		if len(rd.SourceURLs) == 0 || rd.TargetURL == "" {
+			log.Warn().Msgf("%s:%d: row needs both a source and a target URL", file, line)
+			continue
+		}
+
+		if v := get(csvColumnResponseType); v != "" {
+			responseType, err := csvResponseType(v)
+			if err != nil {
+				log.Warn().Msgf("%s:%d: %s", file, line, err)
+				continue
+			}
+			rd.ResponseType = &responseType
+		}
+
+		if rd.ForwardParams, err = csvBool(get(csvColumnForwardParams)); err != nil {
+			log.Warn().Msgf("%s:%d: forward params: %s", file, line, err)
+			continue
+		}
+
+		if rd.ForwardPath, err = csvBool(get(csvColumnForwardPath)); err != nil {
+			log.Warn().Msgf("%s:%d: forward path: %s", file, line, err)
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s|%s|%s|%s", rd.ID, rd.TargetURL, csvValue(rd.ResponseType), csvValue(rd.ForwardParams), csvValue(rd.ForwardPath))
+
+		if i, ok := groups[key]; ok {
+			(*rs)[i].SourceURLs = append((*rs)[i].SourceURLs, rd.SourceURLs...)
+			(*rs)[i].Lines = append((*rs)[i].Lines, line)
+			continue
+		}
+
+		groups[key] = len(*rs)
+		*rs = append(*rs, rd)
+	}
+
+	return
+}
+
+func (rs *CSVRedirects) Defaults() {
+	for i := range *rs {
+		r := &(*rs)[i]
+
+		if r.ForwardParams == nil {
+			r.ForwardParams = &defaultForwardParams
+		}
+		if r.ForwardPath == nil {
+			r.ForwardPath = &defaultForwardPath
+		}
+		if r.ResponseType == nil {
+			r.ResponseType = &defaultResponseType
+		}
+	}
+
+	return
+}
+
+// csvDelimiter picks comma, semicolon or tab, whichever the header line uses
+// most, as spreadsheets export with any of them.
+func csvDelimiter(content []byte) rune {
+	header := string(content)
+	if i := strings.IndexByte(header, '\n'); i != -1 {
+		header = header[:i]
+	}
+
+	delimiter, count := ',', strings.Count(header, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(d)); n > count {
+			delimiter, count = d, n
+		}
+	}
+
+	return delimiter
+}
+
+// csvResponseType accepts a response type or the status code it uses.
+func csvResponseType(v string) (string, error) {
+	switch strings.ToLower(v) {
+	case "moved_permanently", "permanent":
+		return "moved_permanently", nil
+	case "found", "temporary", "temp":
+		return "found", nil
+	case "temporary_redirect", "permanent_redirect":
+		return strings.ToLower(v), nil
+	}
+
+	code, err := strconv.Atoi(v)
+	if err != nil {
+		return "", fmt.Errorf("unknown response type %s", v)
+	}
+
+	responseType, ok := responseTypeForStatus(code)
+	if !ok {
+		return "", fmt.Errorf("status %d is not a redirect", code)
+	}
+
+	return responseType, nil
+}
+
+func csvBool(v string) (*bool, error) {
+	var b bool
+
+	switch strings.ToLower(v) {
+	case "":
+		return nil, nil
+	case "true", "yes", "y", "1":
+		b = true
+	case "false", "no", "n", "0":
+		b = false
+	default:
+		return nil, fmt.Errorf("invalid boolean %s", v)
+	}
+
+	return &b, nil
+}
+
+func csvValue(v interface{}) string {
+	switch t := v.(type) {
+	case *bool:
+		if t != nil {
+			return strconv.FormatBool(*t)
+		}
+	case *string:
+		if t != nil {
+			return *t
+		}
+	}
+
+	return ""
+}
+
+// exportCSV writes the rules as the CSV output, which reads back with the csv
+// import format.
+func exportCSV(ctx context.Context, c *easyredir.Client, w io.Writer) error {
+	rules, err := c.ListRules(ctx, &easyredir.RulesOptions{})
+	if err != nil {
+		return fmt.Errorf("exportCSV: %w", err)
+	}
+
+	if err = rules.Output(&easyredir.OutputOptions{Format: easyredir.OutputCSV, Writer: w}); err != nil {
+		return fmt.Errorf("exportCSV: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CSVRedirect) rule() (rule easyredir.Rule) {
+	rule.Data.Attributes.ForwardParams = *r.ForwardParams
+	rule.Data.Attributes.ForwardPath = *r.ForwardPath
+	rule.Data.Attributes.ResponseType = *r.ResponseType
+	rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, r.SourceURLs...)
+	rule.Data.Attributes.TargetURL = r.TargetURL
+
+	return rule
+}
+
+func (rs *CSVRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file)
+	rs.Defaults()
+
+	return rs.redirects()
+}
+
+func (rs *CSVRedirects) redirects() Redirects {
+	return configRedirects(*rs)
+}
+
+func (r *CSVRedirect) Print() {
+	printConfig(csvPrintTemplate, r)
+}
@@ -0,0 +1,229 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ExportFormat is an export format. Export writes the rules in the account,
+// and for some formats its hosts, to w.
+type ExportFormat struct {
+	Name   string
+	Export func(ctx context.Context, c *easyredir.Client, w io.Writer) error
+}
+
+// defaultExportFormat is written when no format is set.
+const defaultExportFormat string = "yaml"
+
+var exportFormats = make(map[string]ExportFormat)
+
+func init() {
+	RegisterExport(ExportFormat{Name: "yaml", Export: writeYAML})
+}
+
+// RegisterExport makes a format available to Export. Formats register
+// themselves from init.
+func RegisterExport(f ExportFormat) {
+	if _, dup := exportFormats[f.Name]; dup {
+		panic(fmt.Sprintf("importer: export format %s registered twice", f.Name))
+	}
+
+	exportFormats[f.Name] = f
+}
+
+// ExportFormats returns the names of the export formats in order.
+func ExportFormats() []string {
+	names := []string{}
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Export writes every rule in the account to w in the given format. The
+// terraform format also writes the hosts.
+func Export(ctx context.Context, options *Options, w io.Writer) error {
+	name := options.Format
+	if name == "" {
+		name = defaultExportFormat
+	}
+
+	f, ok := exportFormats[name]
+	if !ok {
+		return fmt.Errorf("Export: unknown format %q, use one of %s", options.Format, strings.Join(ExportFormats(), ", "))
+	}
+
+	if err := f.Export(ctx, options.Client, w); err != nil {
+		return fmt.Errorf("Export: %w", err)
+	}
+
+	return nil
+}
+
+// ruleExporter adapts a format that only needs the rules.
+func ruleExporter(export func(rs []easyredir.Rule, w io.Writer) error) func(context.Context, *easyredir.Client, io.Writer) error {
+	return func(ctx context.Context, c *easyredir.Client, w io.Writer) error {
+		rules, err := c.ListRules(ctx, &easyredir.RulesOptions{})
+		if err != nil {
+			return err
+		}
+
+		rs := []easyredir.Rule{}
+		for i := range rules.Data {
+			rs = append(rs, rules.Rule(i))
+		}
+
+		return export(rs, w)
+	}
+}
+
+func writeYAML(ctx context.Context, c *easyredir.Client, w io.Writer) error {
+	rs, err := exportYAML(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(rs)
+	if err != nil {
+		return fmt.Errorf("writeYAML: unable to marshal yaml: %w", err)
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// exportYAML builds the spec that Apply would reconcile the account to
+// without changes. Every source carries the full settings of its host.
+func exportYAML(ctx context.Context, c *easyredir.Client) (rs YAMLRedirects, err error) {
+	rules, err := c.ListRules(ctx, &easyredir.RulesOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("exportYAML: %w", err)
+	}
+
+	hosts, err := c.ListHosts(ctx, &easyredir.HostsOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("exportYAML: %w", err)
+	}
+
+	options := make(map[string]YAMLRedirectSourceOptions)
+
+	for _, h := range hosts.Data {
+		host := easyredir.Host{}
+		host.Data.ID = h.ID
+
+		if err = c.GetHost(ctx, &host); err != nil {
+			return nil, fmt.Errorf("exportYAML: %w", err)
+		}
+
+		options[strings.ToLower(host.Data.Attributes.Name)] = sourceOptions(&host)
+	}
+
+	for i := range rules.Data {
+		rule := rules.Rule(i)
+		a := rule.Data.Attributes
+
+		r := YAMLRedirect{
+			TargetURL:     stringPtr(a.TargetURL),
+			ForwardParams: boolPtr(a.ForwardParams),
+			ForwardPath:   boolPtr(a.ForwardPath),
+			ResponseType:  stringPtr(a.ResponseType),
+		}
+
+		for _, s := range a.SourceUrls {
+			r.Sources = append(r.Sources, YAMLRedirectSource{
+				URL:     stringPtr(exportSource(s)),
+				Options: options[strings.ToLower(sourceHost(s))],
+			})
+		}
+
+		rs = append(rs, r)
+	}
+
+	return rs, nil
+}
+
+// sourceOptions is the inverse of applySourceOptions.
+func sourceOptions(host *easyredir.Host) (o YAMLRedirectSourceOptions) {
+	a := host.Data.Attributes
+
+	o.MatchOptions.CaseInsensitive = interfaceBool(a.MatchOptions.CaseInsensitive)
+	o.MatchOptions.SlashInsensitive = interfaceBool(a.MatchOptions.SlashInsensitive)
+
+	o.NotFoundAction.ForwardParams = interfaceBool(a.NotFoundAction.ForwardParams)
+	o.NotFoundAction.ForwardPath = interfaceBool(a.NotFoundAction.ForwardPath)
+	if a.NotFoundAction.Custom404BodyPresent || a.NotFoundAction.Custom404Body != "" {
+		o.NotFoundAction.Custom404Body = stringPtr(a.NotFoundAction.Custom404Body)
+	}
+	if a.NotFoundAction.ResponseCode != 0 {
+		o.NotFoundAction.ResponseCode = intPtr(a.NotFoundAction.ResponseCode)
+	}
+	if v, ok := a.NotFoundAction.ResponseURL.(string); ok && v != "" {
+		o.NotFoundAction.ResponseURL = stringPtr(v)
+	}
+
+	o.Security.HTTPSUpgrade = interfaceBool(a.Security.HTTPSUpgrade)
+	o.Security.PreventForeignEmbedding = interfaceBool(a.Security.PreventForeignEmbedding)
+	o.Security.HSTSIncludeSubDomains = interfaceBool(a.Security.HstsIncludeSubDomains)
+	o.Security.HSTSPreload = interfaceBool(a.Security.HstsPreload)
+
+	// Without a max age the header is not sent, which the spec writes as -1.
+	switch v := a.Security.HstsMaxAge.(type) {
+	case float64:
+		o.Security.HSTSMaxAge = intPtr(int(v))
+	case int:
+		o.Security.HSTSMaxAge = intPtr(v)
+	default:
+		o.Security.HSTSMaxAge = intPtr(defaultSecurityHSTSMaxAge)
+	}
+
+	return o
+}
+
+// exportSource drops the trailing slash the API adds to bare hosts so the
+// source matches what was originally imported.
+func exportSource(s string) string {
+	raw := s
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+
+	if err == nil && u.Path == "/" && u.RawQuery == "" {
+		return strings.TrimSuffix(s, "/")
+	}
+
+	return s
+}
+
+func interfaceBool(v interface{}) *bool {
+	b, ok := v.(bool)
+	if !ok {
+		return nil
+	}
+
+	return &b
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
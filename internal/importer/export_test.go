@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+// ruleSummary is a rule reduced to what every export format can carry, keyed
+// by source URL so grouping differences do not matter. Path forwarding
+// formats add a trailing slash, which is ignored, and some formats always
+// keep the query string.
+func ruleSummary(rs Redirects, alwaysParams bool) []string {
+	summary := []string{}
+
+	for _, r := range rs.Redirects {
+		a := r.Rule.Data.Attributes
+		params := a.ForwardParams || alwaysParams
+
+		for _, s := range a.SourceUrls {
+			source := strings.TrimSuffix(exportSource(s), "/")
+			target := strings.TrimSuffix(a.TargetURL, "/")
+			summary = append(summary, fmt.Sprintf("%s -> %s %s path=%t params=%t", source, target, a.ResponseType, a.ForwardPath, params))
+		}
+	}
+
+	sort.Strings(summary)
+
+	return summary
+}
+
+// exportRules covers exact and path forwarding rules and a rule with several
+// sources.
+func exportRules() []easyredir.Rule {
+	exact := easyredirtest.NewRule("https://new.com/a", "old.com/a")
+
+	docs := easyredirtest.NewRule("https://new.com/docs", "old.com/docs")
+	docs.Data.Attributes.ResponseType = "found"
+	docs.Data.Attributes.ForwardPath = true
+	docs.Data.Attributes.ForwardParams = true
+
+	several := easyredirtest.NewRule("https://new.com/xy", "other.com/x", "other.com/y")
+	several.Data.Attributes.ForwardPath = true
+
+	return []easyredir.Rule{exact, docs, several}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	tests := []struct {
+		format       string
+		file         string
+		importAs     string
+		alwaysParams bool
+	}{
+		{format: "yaml", file: "spec.yaml", importAs: "yaml"},
+		{format: "csv", file: "redirects.csv", importAs: "csv"},
+		{format: "netlify", file: "_redirects", importAs: "netlify", alwaysParams: true},
+		{format: "netlify-toml", file: "netlify.toml", importAs: "netlify-toml", alwaysParams: true},
+		{format: "vercel", file: "vercel.json", importAs: "vercel", alwaysParams: true},
+		{format: "cloudflare", file: "redirects.json", importAs: "cloudflare"},
+		{format: "cloudflare-csv", file: "redirects.csv", importAs: "cloudflare"},
+		{format: "cloudflare-pagerules", file: "pagerules.json", importAs: "cloudflare-pagerules", alwaysParams: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			for _, r := range exportRules() {
+				s.MustAddRule(t, r)
+			}
+
+			var w bytes.Buffer
+			if err := Export(testContext(t), &Options{Format: tt.format, Client: c}, &w); err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+
+			file := writeFile(t, tt.file, w.String())
+
+			format, err := Detect(file)
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if format != tt.importAs {
+				t.Errorf("Detect = %q, want %q", format, tt.importAs)
+			}
+
+			want, err := c.ListRules(testContext(t), nil)
+			if err != nil {
+				t.Fatalf("ListRules: %v", err)
+			}
+
+			live := Redirects{}
+			for i := range want.Data {
+				live.Redirects = append(live.Redirects, Redirect{Rule: want.Rule(i)})
+			}
+
+			got := formats[tt.importAs].New().Parse(file, &Options{})
+
+			if g, l := ruleSummary(got, tt.alwaysParams), ruleSummary(live, tt.alwaysParams); !reflect.DeepEqual(g, l) {
+				t.Errorf("round trip = %q, want %q\n%s", g, l, w.String())
+			}
+		})
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	_, c := easyredirtest.NewClient(t)
+
+	var w bytes.Buffer
+	if err := Export(testContext(t), &Options{Format: "bogus", Client: c}, &w); err == nil {
+		t.Fatal("Export: want an error for an unknown format")
+	}
+}
+
+func TestExportNetlifySchemes(t *testing.T) {
+	rules := []easyredir.Rule{
+		easyredirtest.NewRule("https://new.com/a", "old.com/a"),
+		easyredirtest.NewRule("https://new.com/b", "https://old.com/b"),
+	}
+
+	var w bytes.Buffer
+	if err := exportNetlify(rules, &w, false); err != nil {
+		t.Fatalf("exportNetlify: %v", err)
+	}
+
+	want := "http://old.com/a https://new.com/a 301!\n" +
+		"https://old.com/a https://new.com/a 301!\n" +
+		"https://old.com/b https://new.com/b 301!\n"
+
+	if got := w.String(); got != want {
+		t.Errorf("exportNetlify() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,17 +1,13 @@
 package importer
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
-	"os"
+	"regexp"
 	"strings"
-	"text/template"
 
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/alecthomas/chroma/quick"
-	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
 
@@ -21,6 +17,22 @@ import (
 //go:embed hiera_print.tmpl
 var hieraPrintTemplate string
 
+//go:embed hiera_rewrite_print.tmpl
+var hieraRewritePrintTemplate string
+
+// hieraContent matches the top level key of a Hiera redirects file.
+var hieraContent = regexp.MustCompile(`(?m)^web_redirects:`)
+
+func init() {
+	Register(Format{
+		Name: "hiera",
+		New:  func() Importer { return &HieraRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".yaml", ".yml") && hieraContent.Match(content)
+		},
+	})
+}
+
 type HieraRedirects []HieraRedirect
 
 type HieraRedirect struct {
@@ -32,56 +44,67 @@ type HieraRedirect struct {
 	SquashPath    bool     `yaml:"squash_path"`
 	Type          int      `yaml:"type"`
 	RewriteRules  []HieraRewriteRule
+	Rewrites      []HieraRewriteRedirect
+	// Replaced holds the host names whose redirect is replaced by a rewrite
+	// of the whole path, which Apache applies first.
+	Replaced []string
 }
 
-type HieraRewriteRules []HieraRewriteRule
-
-type HieraRewriteRule struct {
-	Pattern string
-	Target  string
-	Flags   HieraRewriteRuleFlags
-}
-
-type HieraRewriteRuleFlags struct {
-	Last               bool // [L]
-	NoEscape           bool // [NE]
-	QueryStringDiscard bool // [QSD]
-	Redirect           int  // [R=x]
+// HieraRewriteRedirect is a rule converted from one of the extra rewrites of
+// a redirect. It applies before the redirect itself.
+type HieraRewriteRedirect struct {
+	Name    string
+	Rewrite string
+	simpleRedirect
 }
 
-func (rs *HieraRedirects) Load(file string) {
+func (rs *HieraRedirects) Load(file string) error {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("Load: %w", err)
 	}
 
 	rawRedirects := make(map[string]map[string]HieraRedirect)
 
-	err = yaml.Unmarshal(data, &rawRedirects)
-	if err != nil {
-		fmt.Println(err)
+	if err = yaml.Unmarshal(data, &rawRedirects); err != nil {
+		return fmt.Errorf("Load: %s: %w", file, err)
 	}
 
+	total, failed := 0, 0
+
 	for k, v := range rawRedirects["web_redirects"] {
 		v.Name = k
 		v.RewriteRules = HieraRewriteRules{}
 		v.parseRewrites()
+		failed += v.convertRewrites(file) + len(v.ExtraRewrites) - len(v.RewriteRules)
+		total += len(v.ExtraRewrites)
 		*rs = append(*rs, v)
 	}
 
-	return
+	if failed > 0 {
+		log.Warn().Msgf("%s: %d of %d extra rewrites could not be converted", file, failed, total)
+	}
+
+	return nil
 }
 
 func (r *HieraRedirect) parseRewrites() {
 	for _, rewrite := range r.ExtraRewrites {
 		rr := HieraRewriteRule{}
 
-		rs := strings.Split(rewrite, " ")
+		rs := strings.Fields(rewrite)
+		if len(rs) < 2 || len(rs) > 3 {
+			log.Warn().Msgf("%s: extra rewrite %q needs a pattern, a target and optional flags", r.Name, rewrite)
+			continue
+		}
 		rr.Pattern = rs[0]
 		rr.Target = rs[1]
 		if len(rs) == 3 {
 			rr.Flags = HieraRewriteRuleFlags{}
-			rr.Flags.parseFlags(rs[2])
+			if err := rr.Flags.parseFlags(rs[2]); err != nil {
+				log.Warn().Msgf("%s: extra rewrite %q: %s", r.Name, rewrite, err)
+				continue
+			}
 		}
 
 		r.RewriteRules = append(r.RewriteRules, rr)
@@ -90,88 +113,150 @@ func (r *HieraRedirect) parseRewrites() {
 	return
 }
 
-func (flags *HieraRewriteRuleFlags) parseFlags(f string) {
-	ft := strings.Trim(f, "[]")
-	fs := strings.Split(ft, ",")
-	for _, v := range fs {
-		switch v {
-		case "R=301":
-			flags.Redirect = 301
-		case "R=302":
-			flags.Redirect = 302
-		case "L":
-			flags.Last = true
-		case "QSD":
-			flags.QueryStringDiscard = true
-		case "NE":
-			flags.NoEscape = true
-		default:
-			fmt.Println("Found unknown flag.")
+// convertRewrites turns the rewrite rules that redirect to a fixed path or
+// prefix into rules for the redirect's hosts, and reports the rest. Relative
+// targets stay on the host the request was for, so they become a rule per
+// host. It returns the number of rewrites that could not be converted.
+func (r *HieraRedirect) convertRewrites(file string) (failed int) {
+	names := append([]string{r.Host}, r.Aliases...)
+	replaced := make(map[string]bool)
+
+	for _, rr := range r.RewriteRules {
+		rewrite := rr.Pattern + " " + rr.Target
+
+		active := []string{}
+		for _, n := range names {
+			if !replaced[n] {
+				active = append(active, n)
+			}
 		}
-	}
-
-	return
-}
 
-func (rs *HieraRedirects) Import(preview bool) {
-	c, err := easyredir.NewClient()
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return
-	}
+		groups := [][]string{active}
+		if !strings.Contains(rr.Target, "://") {
+			groups = [][]string{}
+			for _, n := range active {
+				groups = append(groups, []string{n})
+			}
+		}
 
-	for _, r := range *rs {
-		r.Print()
+		var err error
+		switch {
+		case len(active) == 0:
+			err = fmt.Errorf("an earlier rewrite already matches every path")
+		case rr.Flags.Forbidden || rr.Flags.Gone:
+			err = fmt.Errorf("the rewrite does not redirect")
+		}
 
-		rule := easyredir.Rule{}
+		converted := []HieraRewriteRedirect{}
 
-		// For now we will always forward params however we need to check the extra_rewrite value in the future.
-		rule.Data.Attributes.ForwardParams = true
+		for _, group := range groups {
+			if err != nil {
+				break
+			}
 
-		// Forward path is the inverse of squash path so we negate it.
-		rule.Data.Attributes.ForwardPath = !r.SquashPath
+			var ar *ApacheRedirect
+			if ar, err = rr.redirect(group, false); err != nil || ar == nil {
+				continue
+			}
 
-		// Only two types of response type (301 or 302) and unless set to 301 we default to 302.
-		if r.Type == 301 {
-			rule.Data.Attributes.ResponseType = "found"
-		} else {
-			rule.Data.Attributes.ResponseType = "moved_permanently"
+			converted = append(converted, HieraRewriteRedirect{
+				Name:           r.Name,
+				Rewrite:        rewrite,
+				simpleRedirect: ar.simpleRedirect,
+			})
 		}
 
-		// Combine host and aliases to create the complete source URLs.
-		rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, r.Host)
-		for _, v := range r.Aliases {
-			rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, v)
+		if err != nil {
+			log.Warn().Msgf("%s: %s: extra rewrite %s cannot be converted: %s", file, r.Name, rewrite, err)
+			failed++
+			continue
 		}
 
-		// The actual target to redirect to.
-		rule.Data.Attributes.TargetURL = r.Redirect
+		if rr.Flags.NoCase {
+			log.Warn().Msgf("%s: %s: extra rewrite %s is case insensitive, which needs the host case_insensitive match option", file, r.Name, rewrite)
+		}
 
-		if preview != true {
-			res, err := c.CreateRule(&rule)
-			if err != nil {
-				log.Error().Err(err).Msg("")
+		if path, _, _ := rewritePath(rr.Pattern); path == "" && len(converted) > 0 {
+			for _, n := range active {
+				replaced[n] = true
+				r.Replaced = append(r.Replaced, n)
 			}
+			log.Warn().Msgf("%s: %s: extra rewrite %s replaces the redirect to %s for %s", file, r.Name, rewrite, r.Redirect, strings.Join(active, ", "))
+		}
+
+		r.Rewrites = append(r.Rewrites, converted...)
+	}
+
+	return failed
+}
+
+func (rs *HieraRedirects) Parse(file string, options *Options) Redirects {
+	if err := rs.Load(file); err != nil {
+		log.Error().Err(err).Msg("")
+		return Redirects{}
+	}
+
+	return rs.redirects()
+}
+
+// redirects lists the rules converted from each redirect's rewrites before
+// the redirect itself, which is left out when rewrites replace it for every
+// host.
+func (rs *HieraRedirects) redirects() (out Redirects) {
+	for i := range *rs {
+		r := &(*rs)[i]
+
+		for j := range r.Rewrites {
+			rw := &r.Rewrites[j]
+			out.Redirects = append(out.Redirects, Redirect{Rule: rw.rule(), Config: rw})
+		}
 
-			res.Print()
+		if rule := r.rule(); len(rule.Data.Attributes.SourceUrls) > 0 {
+			out.Redirects = append(out.Redirects, Redirect{Rule: rule, Config: r})
 		}
 	}
 
-	return
+	return out
 }
 
-func (r *HieraRedirect) Print() {
-	fmt.Printf("%s:\n", text.FgCyan.Sprint("CONFIG"))
-	fmt.Println()
+func (r *HieraRedirect) rule() (rule easyredir.Rule) {
+	// The redirect keeps the query string; extra rewrites become their own
+	// rules with their own query string handling.
+	rule.Data.Attributes.ForwardParams = true
+
+	// Forward path is the inverse of squash path so we negate it.
+	rule.Data.Attributes.ForwardPath = !r.SquashPath
+
+	// Only two types of response type (301 or 302) and unless set to 301 we default to 302.
+	if r.Type == 301 {
+		rule.Data.Attributes.ResponseType = "found"
+	} else {
+		rule.Data.Attributes.ResponseType = "moved_permanently"
+	}
+
+	// Combine host and aliases to create the complete source URLs, leaving
+	// out hosts whose redirect a rewrite replaces.
+	replaced := make(map[string]bool)
+	for _, v := range r.Replaced {
+		replaced[v] = true
+	}
 
-	var w bytes.Buffer
+	for _, v := range append([]string{r.Host}, r.Aliases...) {
+		if !replaced[v] {
+			rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, v)
+		}
+	}
 
-	t := template.Must(template.New("").Parse(hieraPrintTemplate))
-	t.Execute(&w, r)
+	// The actual target to redirect to.
+	rule.Data.Attributes.TargetURL = r.Redirect
 
-	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
+	return rule
+}
 
-	fmt.Println()
+func (r *HieraRedirect) Print() {
+	printConfig(hieraPrintTemplate, r)
+}
 
-	return
+func (r *HieraRewriteRedirect) Print() {
+	printConfig(hieraRewritePrintTemplate, r)
 }
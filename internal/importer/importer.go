@@ -1,32 +1,244 @@
 package importer
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog/log"
 )
 
 type Options struct {
-	Format  string
-	File    string
-	Preview bool
-}
-
-func Import(options *Options) {
-	switch options.Format {
-	case "hiera":
-		r := HieraRedirects{}
-		r.Load(options.File)
-		r.Import(options.Preview)
-	case "yaml":
-		r := YAMLRedirects{}
-		r.Load(options.File)
-		r.Defaults()
-		r.Import(options.Preview)
-	case "puppet":
-		r := PuppetRedirects{}
-		r.Load(options.File)
-		r.Defaults()
-		r.Import(options.Preview)
-	default:
-		fmt.Println("Unknown format.")
+	Format     string
+	File       string
+	Preview    bool
+	Host       string
+	Path       string
+	Prune      bool
+	OnConflict string
+	Client     *easyredir.Client
+}
+
+// Redirects is the normalized form every format is read into: the rules to
+// create, in order, and the options to set on their source hosts.
+type Redirects struct {
+	Redirects []Redirect
+	Hosts     map[string]YAMLRedirectSourceOptions
+}
+
+// Redirect is one rule and the configuration it was read from, which is
+// printed when importing.
+type Redirect struct {
+	Rule   easyredir.Rule
+	Config interface{ Print() }
+}
+
+// Import reads the file in the given format, or the detected format when none
+// is set, and submits its rules.
+func Import(ctx context.Context, options *Options) {
+	name := options.Format
+	if name == "" {
+		var err error
+		if name, err = Detect(options.File); err != nil {
+			log.Error().Err(err).Msg("")
+			return
+		}
+		log.Info().Msgf("Importing %s as %s.", options.File, name)
+	}
+
+	f, ok := formats[name]
+	if !ok {
+		log.Error().Err(fmt.Errorf("Import: unknown format %q, use one of %s", name, strings.Join(Formats(), ", "))).Msg("")
+		return
+	}
+
+	rs := f.New().Parse(options.File, options)
+	rs.Import(ctx, options.Client, options.Preview, options.OnConflict)
+}
+
+// Import validates the rules and checks them for conflicts, then prints each
+// one and, unless previewing, submits it and sets the options of its hosts.
+func (rs *Redirects) Import(ctx context.Context, c *easyredir.Client, preview bool, onConflict string) {
+	valid := []Redirect{}
+	rules := []easyredir.Rule{}
+
+	for _, r := range rs.Redirects {
+		if err := r.validate(); err != nil {
+			r.Config.Print()
+			log.Warn().Msgf("skipping rule: %s", err)
+			continue
+		}
+
+		valid = append(valid, r)
+		rules = append(rules, r.Rule)
+	}
+
+	decisions, err := checkConflicts(ctx, c, rules, onConflict)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		if preview != true {
+			return
+		}
 	}
+
+	updated := make(map[string]bool)
+
+	for i, r := range valid {
+		r.Config.Print()
+
+		if preview == true {
+			continue
+		}
+
+		d := decisions[i]
+		if d.Action == ActionSkip {
+			continue
+		}
+
+		res, err := d.submit(ctx, c)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+
+			// Stop on interrupt rather than failing every remaining rule.
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		res.Print()
+
+		// Hosts are matched to sources by name, as the API does not keep
+		// them in source order.
+		ids := make(map[string]string)
+		for _, h := range res.Included {
+			ids[strings.ToLower(h.Attributes.Name)] = h.ID
+		}
+
+		for _, u := range res.Data.Attributes.SourceUrls {
+			name := strings.ToLower(sourceHost(u))
+
+			o, ok := rs.Hosts[name]
+			if !ok || updated[name] {
+				continue
+			}
+
+			id, ok := ids[name]
+			if !ok {
+				log.Warn().Msgf("host %s is not included in the response, its options are not set", name)
+				continue
+			}
+			updated[name] = true
+
+			host := &easyredir.Host{}
+			host.Data.ID = id
+			applySourceOptions(host, o)
+
+			res, err := c.UpdateHost(ctx, host)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				continue
+			}
+
+			res.Print()
+		}
+	}
+
+	return
+}
+
+// validate checks the rule before anything is sent, so one bad redirect does
+// not fail halfway through an import.
+func (r *Redirect) validate() error {
+	a := r.Rule.Data.Attributes
+
+	if len(a.SourceUrls) == 0 {
+		return fmt.Errorf("no source URLs")
+	}
+
+	for _, s := range a.SourceUrls {
+		u, err := sourceURL(s)
+		if err != nil || u.Hostname() == "" {
+			return fmt.Errorf("invalid source URL %q", s)
+		}
+	}
+
+	u, err := url.Parse(a.TargetURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("target URL %q is not an absolute http or https URL", a.TargetURL)
+	}
+
+	if t, _ := responseTypeForStatus(statusForResponseType(a.ResponseType)); t != a.ResponseType {
+		return fmt.Errorf("unknown response type %q", a.ResponseType)
+	}
+
+	return nil
+}
+
+func Apply(ctx context.Context, options *Options) {
+	r, err := loadSpec(options.File)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	r.Apply(ctx, options.Client, options.Prune)
+}
+
+func Plan(ctx context.Context, options *Options) {
+	r, err := loadSpec(options.File)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	r.Plan(ctx, options.Client, options.Prune)
+}
+
+// loadSpec reads and validates a YAML spec and fills in the rule defaults.
+// Host options are left as declared, so only those are changed on live hosts.
+func loadSpec(file string) (YAMLRedirects, error) {
+	r := YAMLRedirects{}
+
+	if err := r.Load(file); err != nil {
+		return nil, err
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.ruleDefaults()
+
+	return r, nil
+}
+
+// responseTypeForStatus maps a redirect status code to a rule response type.
+func responseTypeForStatus(code int) (string, bool) {
+	switch code {
+	case 301:
+		return "moved_permanently", true
+	case 302:
+		return "found", true
+	case 307:
+		return "temporary_redirect", true
+	case 308:
+		return "permanent_redirect", true
+	}
+
+	return "", false
+}
+
+// statusForResponseType is the inverse of responseTypeForStatus.
+func statusForResponseType(responseType string) int {
+	for _, code := range []int{301, 302, 307, 308} {
+		if t, _ := responseTypeForStatus(code); t == responseType {
+			return code
+		}
+	}
+
+	return 301
 }
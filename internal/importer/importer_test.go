@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+// writeFile writes content to a file with the given name in a temporary
+// directory and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return file
+}
+
+// targets maps each source URL on the server to the target of its rule.
+func targets(s *easyredirtest.Server) map[string]string {
+	m := make(map[string]string)
+
+	for _, r := range s.Rules() {
+		for _, src := range r.Data.Attributes.SourceUrls {
+			m[exportSource(src)] = r.Data.Attributes.TargetURL
+		}
+	}
+
+	return m
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "yaml",
+			file:    "spec.yaml",
+			content: "- sources:\n  - url: old.com\n  target_url: https://new.com\n",
+			want:    "yaml",
+		},
+		{
+			name:    "hiera",
+			file:    "common.yaml",
+			content: "web_redirects:\n  old:\n    apache_hosts: old.com\n",
+			want:    "hiera",
+		},
+		{
+			name:    "csv",
+			file:    "redirects.csv",
+			content: "source_urls,target_url\nold.com,https://new.com\n",
+			want:    "csv",
+		},
+		{
+			name:    "cloudflare csv",
+			file:    "redirects.csv",
+			content: "source_url,target_url,status_code,preserve_query_string\nold.com/,https://new.com,301,false\n",
+			want:    "cloudflare",
+		},
+		{
+			name:    "cloudflare page rules",
+			file:    "pagerules.json",
+			content: `[{"targets": [], "actions": [{"id": "forwarding_url"}]}]`,
+			want:    "cloudflare-pagerules",
+		},
+		{
+			name:    "netlify",
+			file:    "_redirects",
+			content: "/old https://new.com 301\n",
+			want:    "netlify",
+		},
+		{
+			name:    "netlify toml",
+			file:    "netlify.toml",
+			content: "[[redirects]]\nfrom = \"/old\"\nto = \"https://new.com\"\n",
+			want:    "netlify-toml",
+		},
+		{
+			name:    "vercel",
+			file:    "vercel.json",
+			content: `{"redirects": [{"source": "/old", "destination": "https://new.com"}]}`,
+			want:    "vercel",
+		},
+		{
+			name:    "nginx",
+			file:    "site.conf",
+			content: "server {\n  server_name old.com;\n  return 301 https://new.com;\n}\n",
+			want:    "nginx",
+		},
+		{
+			name:    "apache",
+			file:    ".htaccess",
+			content: "Redirect 301 /old https://new.com\n",
+			want:    "apache",
+		},
+		{
+			name:    "puppet",
+			file:    "site.pp",
+			content: "$controllers = {}\n",
+			want:    "puppet",
+		},
+		{
+			name:    "unknown",
+			file:    "notes.txt",
+			content: "nothing to see\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(writeFile(t, tt.file, tt.content))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Detect = %q, want an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Detect = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportHostOptions(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+
+	file := writeFile(t, "spec.yaml", `
+- sources:
+  - url: old.com/a
+  - url: old.com/b
+  - url: www.old.com/a
+    options:
+      match_options:
+        case_insensitive: true
+  target_url: https://new.com
+`)
+
+	rs := (&YAMLRedirects{}).Parse(file, &Options{})
+	rs.Import(testContext(t), c, false, "")
+
+	hosts := make(map[string]easyredir.Host)
+	for _, h := range s.Hosts() {
+		hosts[h.Data.Attributes.Name] = h
+	}
+
+	// Two sources share a host, so hosts and sources are not in step.
+	if got := hosts["www.old.com"].Data.Attributes.MatchOptions.CaseInsensitive; got != true {
+		t.Errorf("www.old.com case_insensitive = %v, want true", got)
+	}
+
+	if got := hosts["old.com"].Data.Attributes.MatchOptions.CaseInsensitive; got == true {
+		t.Errorf("old.com case_insensitive = %v, want false", got)
+	}
+}
+
+func TestImportSkipsInvalid(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+
+	file := writeFile(t, "spec.yaml", `
+- sources:
+  - url: missing-target.com
+- sources:
+  - options: {}
+  target_url: https://missing-url.com
+- sources:
+  - url: old.com
+  target_url: https://new.com
+`)
+
+	rs := (&YAMLRedirects{}).Parse(file, &Options{})
+	rs.Import(testContext(t), c, false, "")
+
+	want := map[string]string{"old.com": "https://new.com"}
+	if got := targets(s); !equalTargets(got, want) {
+		t.Errorf("rules = %v, want %v", got, want)
+	}
+}
+
+func TestCheckConflictsUnknownMode(t *testing.T) {
+	rules := []easyredir.Rule{easyredirtest.NewRule("https://new.com", "old.com")}
+
+	if _, err := checkConflicts(testContext(t), nil, rules, "foo"); err == nil {
+		t.Errorf("checkConflicts() error = nil, want unknown conflict mode")
+	}
+}
+
+func equalTargets(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
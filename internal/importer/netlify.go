@@ -0,0 +1,366 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed netlify_print.tmpl
+var netlifyPrintTemplate string
+
+func init() {
+	Register(Format{
+		Name: "netlify",
+		New:  func() Importer { return &NetlifyRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return filepath.Base(file) == "_redirects"
+		},
+	})
+
+	Register(Format{
+		Name: "netlify-toml",
+		New: func() Importer {
+			return ImporterFunc(func(file string, options *Options) Redirects {
+				rs := NetlifyRedirects{}
+				rs.LoadTOML(file, options.Host)
+
+				return rs.redirects()
+			})
+		},
+		Detect: func(file string, content []byte) bool {
+			return filepath.Base(file) == "netlify.toml" || (hasExtension(file, ".toml") && bytes.Contains(content, []byte("[[redirects]]")))
+		},
+	})
+
+	RegisterExport(ExportFormat{
+		Name: "netlify",
+		Export: ruleExporter(func(rs []easyredir.Rule, w io.Writer) error {
+			return exportNetlify(rs, w, false)
+		}),
+	})
+
+	RegisterExport(ExportFormat{
+		Name: "netlify-toml",
+		Export: ruleExporter(func(rs []easyredir.Rule, w io.Writer) error {
+			return exportNetlify(rs, w, true)
+		}),
+	})
+}
+
+type NetlifyRedirects []NetlifyRedirect
+
+// NetlifyRedirect is a redirect from a _redirects file or the [[redirects]]
+// tables of netlify.toml. Line is zero for netlify.toml.
+type NetlifyRedirect struct {
+	Line   int
+	From   string
+	To     string
+	Status int
+	simpleRedirect
+}
+
+type netlifyTOML struct {
+	Redirects []netlifyTOMLRedirect `toml:"redirects"`
+}
+
+type netlifyTOMLRedirect struct {
+	From       string                 `toml:"from"`
+	To         string                 `toml:"to"`
+	Status     int                    `toml:"status,omitempty"`
+	Force      bool                   `toml:"force,omitempty"`
+	Query      map[string]string      `toml:"query,omitempty"`
+	Conditions map[string]interface{} `toml:"conditions,omitempty"`
+	Headers    map[string]string      `toml:"headers,omitempty"`
+	Signed     string                 `toml:"signed,omitempty"`
+}
+
+// Load reads a _redirects file. Paths without a domain use the given host.
+func (rs *NetlifyRedirects) Load(file string, host string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	line := 0
+
+	for s.Scan() {
+		line++
+
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// Comments may follow the rule.
+		for i, v := range fields {
+			if strings.HasPrefix(v, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+
+		if len(fields) < 2 {
+			log.Warn().Msgf("%s:%d: a redirect needs a from and a to path", file, line)
+			continue
+		}
+
+		// Query parameter matches come between from and to.
+		if strings.Contains(fields[1], "=") && !strings.Contains(fields[1], "/") {
+			log.Warn().Msgf("%s:%d: query parameter matches cannot be converted", file, line)
+			continue
+		}
+
+		status := 301
+		extra := fields[2:]
+
+		if len(extra) > 0 && !strings.Contains(extra[0], "=") {
+			code, err := strconv.Atoi(strings.TrimSuffix(extra[0], "!"))
+			if err != nil {
+				log.Warn().Msgf("%s:%d: invalid status %s", file, line, extra[0])
+				continue
+			}
+			status = code
+			extra = extra[1:]
+		}
+
+		if len(extra) > 0 {
+			log.Warn().Msgf("%s:%d: conditions %s cannot be converted", file, line, strings.Join(extra, " "))
+			continue
+		}
+
+		r, err := netlifyRedirect(fields[0], fields[1], status, host)
+		if err != nil {
+			log.Warn().Msgf("%s:%d: %s", file, line, err)
+			continue
+		}
+		r.Line = line
+
+		*rs = append(*rs, *r)
+	}
+
+	if err := s.Err(); err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	return
+}
+
+// LoadTOML reads the [[redirects]] tables of a netlify.toml file.
+func (rs *NetlifyRedirects) LoadTOML(file string, host string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	config := netlifyTOML{}
+	if err = toml.Unmarshal(content, &config); err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
+	}
+
+	for i, v := range config.Redirects {
+		if len(v.Query) > 0 || len(v.Conditions) > 0 || len(v.Headers) > 0 || v.Signed != "" {
+			log.Warn().Msgf("%s: redirect %d from %s: query, conditions, headers and signed cannot be converted", file, i+1, v.From)
+			continue
+		}
+
+		status := v.Status
+		if status == 0 {
+			status = 301
+		}
+
+		r, err := netlifyRedirect(v.From, v.To, status, host)
+		if err != nil {
+			log.Warn().Msgf("%s: redirect %d: %s", file, i+1, err)
+			continue
+		}
+
+		*rs = append(*rs, *r)
+	}
+
+	return
+}
+
+// netlifyRedirect converts a from and to pair. A trailing splat in from
+// forwards the path when to ends with :splat; other placeholders cannot be
+// expressed as a rule. Netlify keeps the query string on redirects.
+func netlifyRedirect(from string, to string, status int, host string) (*NetlifyRedirect, error) {
+	r := &NetlifyRedirect{
+		From:           from,
+		To:             to,
+		Status:         status,
+		simpleRedirect: simpleRedirect{ForwardParams: true},
+	}
+
+	responseType, ok := responseTypeForStatus(status)
+	if !ok {
+		return nil, fmt.Errorf("status %d from %s is not a redirect", status, from)
+	}
+	r.ResponseType = responseType
+
+	source, err := splatSource(from, host, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(from, "*") {
+		if !strings.HasSuffix(to, ":splat") {
+			return nil, fmt.Errorf("%s matches a path prefix but %s does not use :splat", from, to)
+		}
+		to = strings.TrimSuffix(to, ":splat")
+		r.ForwardPath = true
+	}
+
+	if strings.Contains(source, "/:") || strings.Contains(source, "*") || strings.Contains(to, "/:") {
+		return nil, fmt.Errorf("placeholders in %s %s cannot be converted", from, r.To)
+	}
+
+	if r.TargetURL, err = absoluteTarget(to, host); err != nil {
+		return nil, err
+	}
+
+	r.SourceURLs = []string{source}
+
+	return r, nil
+}
+
+// splatSource turns a from path or URL into a source URL. A trailing splat is
+// removed and paths without a domain are placed on the host.
+func splatSource(from string, host string, splat string) (string, error) {
+	source := strings.TrimSuffix(from, splat)
+
+	if i := strings.Index(source, "://"); i != -1 {
+		source = source[i+3:]
+	} else {
+		if host == "" {
+			return "", fmt.Errorf("%s has no domain, set the host", from)
+		}
+		source = host + source
+	}
+
+	if strings.HasSuffix(source, "/") && strings.Count(source, "/") == 1 {
+		source = strings.TrimSuffix(source, "/")
+	}
+
+	return source, nil
+}
+
+// absoluteTarget places relative targets on the host.
+func absoluteTarget(to string, host string) (string, error) {
+	if strings.Contains(to, "://") {
+		return to, nil
+	}
+
+	if host == "" || !strings.HasPrefix(to, "/") {
+		return "", fmt.Errorf("target %s is relative, set the host", to)
+	}
+
+	return "https://" + host + to, nil
+}
+
+// exportNetlify writes rules as a _redirects file, or netlify.toml when
+// asTOML is set. Every source becomes a forced domain level redirect.
+func exportNetlify(rules []easyredir.Rule, w io.Writer, asTOML bool) error {
+	config := netlifyTOML{}
+
+	for _, rule := range rules {
+		a := rule.Data.Attributes
+
+		if !a.ForwardParams {
+			log.Warn().Msgf("rule %s drops the query string, which Netlify always keeps", rule.Data.ID)
+		}
+
+		// A source without a scheme matches both, and Netlify needs an
+		// entry for each.
+		froms := []string{}
+		for _, s := range a.SourceUrls {
+			if strings.Contains(s, "://") {
+				froms = append(froms, s)
+				continue
+			}
+			froms = append(froms, "http://"+s, "https://"+s)
+		}
+
+		for _, from := range froms {
+			to := a.TargetURL
+			if a.ForwardPath {
+				from = strings.TrimRight(from, "/") + "/*"
+				to = strings.TrimRight(to, "/") + "/:splat"
+			}
+
+			config.Redirects = append(config.Redirects, netlifyTOMLRedirect{
+				From:   from,
+				To:     to,
+				Status: statusForResponseType(a.ResponseType),
+				Force:  true,
+			})
+		}
+	}
+
+	if asTOML {
+		data, err := toml.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("exportNetlify: unable to marshal toml: %w", err)
+		}
+
+		if _, err = w.Write(data); err != nil {
+			return fmt.Errorf("exportNetlify: %w", err)
+		}
+
+		return nil
+	}
+
+	for _, r := range config.Redirects {
+		if _, err := fmt.Fprintf(w, "%s %s %d!\n", r.From, r.To, r.Status); err != nil {
+			return fmt.Errorf("exportNetlify: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (rs *NetlifyRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file, options.Host)
+
+	return rs.redirects()
+}
+
+// redirects leaves out repeats. Sources lose their scheme, so the http and
+// https entries written for a source become a single rule again.
+func (rs *NetlifyRedirects) redirects() Redirects {
+	unique := NetlifyRedirects{}
+	seen := make(map[string]bool)
+
+	for _, r := range *rs {
+		key := fmt.Sprint(r.simpleRedirect)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		unique = append(unique, r)
+	}
+
+	return configRedirects(unique)
+}
+
+func (r *NetlifyRedirect) Print() {
+	printConfig(netlifyPrintTemplate, r)
+}
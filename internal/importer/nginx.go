@@ -0,0 +1,575 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed nginx_print.tmpl
+var nginxPrintTemplate string
+
+// nginxContent matches the opening of a server or location block.
+var nginxContent = regexp.MustCompile(`(?m)^\s*(server|location)\b[^;{]*\{`)
+
+func init() {
+	Register(Format{
+		Name: "nginx",
+		New:  func() Importer { return &NginxRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return nginxContent.Match(content)
+		},
+	})
+}
+
+type NginxRedirects []NginxRedirect
+
+type NginxRedirect struct {
+	Line      int
+	Directive string
+	simpleRedirect
+}
+
+// nginxDirective is a single statement in an nginx configuration. Block
+// directives such as server and location hold their contents in Children.
+type nginxDirective struct {
+	Name     string
+	Args     []string
+	Line     int
+	Children []nginxDirective
+}
+
+func (rs *NginxRedirects) Load(file string) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	directives, err := parseNginx(string(content))
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
+	}
+
+	seen := make(map[string]bool)
+
+	var walk func(ds []nginxDirective)
+	walk = func(ds []nginxDirective) {
+		for _, d := range ds {
+			switch d.Name {
+			case "server":
+				for _, r := range nginxServer(file, d) {
+					// The same redirect is often repeated for the http and
+					// https server blocks.
+					key := strings.Join(r.SourceURLs, ",") + " " + r.TargetURL
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+
+					*rs = append(*rs, r)
+				}
+			case "include":
+				log.Warn().Msgf("%s:%d: include %s is not followed", file, d.Line, strings.Join(d.Args, " "))
+			default:
+				walk(d.Children)
+			}
+		}
+	}
+	walk(directives)
+
+	return
+}
+
+// nginxServer converts the redirects in a server block. Redirects are taken
+// from the server level and from exact and prefix location blocks.
+func nginxServer(file string, server nginxDirective) (rs NginxRedirects) {
+	names := []string{}
+
+	for _, d := range server.Children {
+		if d.Name != "server_name" {
+			continue
+		}
+
+		for _, n := range d.Args {
+			if n == "_" || n == "" || strings.HasPrefix(n, "~") || strings.Contains(n, "*") {
+				log.Warn().Msgf("%s:%d: server_name %s cannot be used as a source URL", file, d.Line, n)
+				continue
+			}
+			names = append(names, strings.TrimPrefix(n, "."))
+		}
+	}
+
+	if len(names) == 0 {
+		return rs
+	}
+
+	// visit converts the redirects in a block and reports whether a return
+	// ends processing of the block.
+	var visit func(ds []nginxDirective, location string, prefix bool) bool
+	visit = func(ds []nginxDirective, location string, prefix bool) bool {
+		for _, d := range ds {
+			switch d.Name {
+			case "location":
+				path, exact, ok := nginxLocation(d.Args)
+				if !ok {
+					log.Warn().Msgf("%s:%d: location %s cannot be converted", file, d.Line, strings.Join(d.Args, " "))
+					continue
+				}
+				visit(d.Children, path, !exact)
+			case "return":
+				r, err := nginxReturn(d, names, location, prefix)
+				if err != nil {
+					log.Warn().Msgf("%s:%d: %s", file, d.Line, err)
+				} else if r != nil {
+					rs = append(rs, *r)
+				}
+				// Nothing after a return is reached.
+				return true
+			case "rewrite":
+				r, err := nginxRewrite(d, names, location)
+				if err != nil {
+					log.Warn().Msgf("%s:%d: %s", file, d.Line, err)
+					continue
+				}
+				if r != nil {
+					rs = append(rs, *r)
+				}
+			case "if":
+				log.Warn().Msgf("%s:%d: if blocks cannot be converted", file, d.Line)
+			}
+		}
+
+		return false
+	}
+
+	// The server level rewrite phase runs before a location is chosen, so its
+	// directives are converted first and take the paths they match.
+	serverLevel, locations := []nginxDirective{}, []nginxDirective{}
+	for _, d := range server.Children {
+		if d.Name == "location" {
+			locations = append(locations, d)
+			continue
+		}
+		serverLevel = append(serverLevel, d)
+	}
+
+	if visit(serverLevel, "", true) {
+		for _, d := range locations {
+			log.Warn().Msgf("%s:%d: location %s is not reached, the server returns first", file, d.Line, strings.Join(d.Args, " "))
+		}
+		return rs
+	}
+
+	serverRedirects := rs
+	rs = nil
+	visit(locations, "", true)
+
+	for _, r := range rs {
+		if sr := nginxShadowed(r, serverRedirects, names[0]); sr != nil {
+			log.Warn().Msgf("%s:%d: %s is not reached, the server level %s on line %d applies first", file, r.Line, r.Directive, sr.Directive, sr.Line)
+			continue
+		}
+		serverRedirects = append(serverRedirects, r)
+	}
+
+	return serverRedirects
+}
+
+// nginxShadowed returns the server level redirect that matches every request
+// the location redirect does.
+func nginxShadowed(r NginxRedirect, server NginxRedirects, name string) *NginxRedirect {
+	path := strings.TrimPrefix(strings.TrimPrefix(r.SourceURLs[0], "http://"), name)
+
+	for i, sr := range server {
+		// A redirect limited to plain http leaves https requests to the
+		// locations.
+		if strings.Contains(sr.SourceURLs[0], "://") {
+			continue
+		}
+
+		sp := strings.TrimPrefix(sr.SourceURLs[0], name)
+
+		if path == sp {
+			return &server[i]
+		}
+
+		if sr.ForwardPath && strings.HasPrefix(path, sp) && (sp == "" || strings.HasSuffix(sp, "/") || path[len(sp)] == '/') {
+			return &server[i]
+		}
+	}
+
+	return nil
+}
+
+// nginxHostVariable matches the variables holding the requested host name.
+var nginxHostVariable = regexp.MustCompile(`\$(\{host\}|\{server_name\}|host\b|server_name\b)`)
+
+// nginxHost replaces $host and $server_name in a target with the server name.
+// With several server names each would need a rule of its own.
+func nginxHost(target string, names []string) (string, error) {
+	if !nginxHostVariable.MatchString(target) {
+		return target, nil
+	}
+
+	if len(names) != 1 {
+		return "", fmt.Errorf("target %s uses the host name, which is only replaced when the server has a single server_name", target)
+	}
+
+	return nginxHostVariable.ReplaceAllLiteralString(target, names[0]), nil
+}
+
+// nginxLocation returns the path of exact (=) and plain prefix locations.
+// Regular expression locations have no equivalent.
+func nginxLocation(args []string) (path string, exact bool, ok bool) {
+	switch {
+	case len(args) == 2 && args[0] == "=":
+		return args[1], true, true
+	case len(args) == 2 && args[0] == "^~":
+		return args[1], false, true
+	case len(args) == 1 && strings.HasPrefix(args[0], "/"):
+		return args[0], false, true
+	}
+
+	return "", false, false
+}
+
+func nginxReturn(d nginxDirective, names []string, location string, prefix bool) (*NginxRedirect, error) {
+	args := d.Args
+
+	// A bare URL is returned as a temporary redirect.
+	if len(args) == 1 && strings.Contains(args[0], "://") {
+		args = []string{"302", args[0]}
+	}
+
+	if len(args) != 2 {
+		return nil, nil
+	}
+
+	code, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("return %s: invalid status code", args[0])
+	}
+
+	responseType, ok := responseTypeForStatus(code)
+	if !ok {
+		return nil, fmt.Errorf("return %d is not a redirect", code)
+	}
+
+	r := &NginxRedirect{
+		Line:      d.Line,
+		Directive: "return " + strings.Join(d.Args, " "),
+	}
+	r.ResponseType = responseType
+
+	target, err := nginxHost(args[1], names)
+	if err != nil {
+		return nil, fmt.Errorf("return %w", err)
+	}
+
+	switch {
+	case strings.HasSuffix(target, "$request_uri"):
+		target = strings.TrimSuffix(target, "$request_uri")
+		r.ForwardPath = prefix
+		r.ForwardParams = true
+
+		// $request_uri repeats the location path, which a rule source strips.
+		if location != "" {
+			target = strings.TrimRight(target, "/") + location
+		}
+	case strings.HasSuffix(target, "$uri$is_args$args"):
+		target = strings.TrimSuffix(target, "$uri$is_args$args")
+		r.ForwardPath = prefix
+		r.ForwardParams = true
+		if location != "" {
+			target = strings.TrimRight(target, "/") + location
+		}
+	case strings.HasSuffix(target, "$uri"):
+		target = strings.TrimSuffix(target, "$uri")
+		r.ForwardPath = prefix
+		if location != "" {
+			target = strings.TrimRight(target, "/") + location
+		}
+	case strings.HasSuffix(target, "$is_args$args"):
+		target = strings.TrimSuffix(target, "$is_args$args")
+		r.ForwardParams = true
+	}
+
+	if strings.Contains(target, "$") {
+		return nil, fmt.Errorf("return target %s uses variables that cannot be converted", d.Args[1])
+	}
+
+	if !strings.Contains(target, "://") {
+		return nil, fmt.Errorf("return target %s is not an absolute URL", d.Args[1])
+	}
+
+	r.TargetURL = target
+	r.SourceURLs = nginxSources(names, location)
+
+	return r, nginxSelfRedirect(r)
+}
+
+func nginxRewrite(d nginxDirective, names []string, location string) (*NginxRedirect, error) {
+	if len(d.Args) != 3 {
+		return nil, fmt.Errorf("rewrite %s does not redirect", strings.Join(d.Args, " "))
+	}
+
+	pattern, replacement, flag := d.Args[0], d.Args[1], d.Args[2]
+
+	r := &NginxRedirect{
+		Line:      d.Line,
+		Directive: "rewrite " + strings.Join(d.Args, " "),
+	}
+
+	switch flag {
+	case "permanent":
+		r.ResponseType, _ = responseTypeForStatus(301)
+	case "redirect":
+		r.ResponseType, _ = responseTypeForStatus(302)
+	default:
+		return nil, fmt.Errorf("rewrite flag %s does not redirect", flag)
+	}
+
+	replacement, err := nginxHost(replacement, names)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite %w", err)
+	}
+
+	// Arguments are appended unless the replacement ends in a question mark.
+	r.ForwardParams = !strings.HasSuffix(replacement, "?")
+	replacement = strings.TrimSuffix(replacement, "?")
+
+	path, forwardPath, ok := rewritePath(pattern)
+	if !ok {
+		return nil, fmt.Errorf("rewrite pattern %s cannot be converted", pattern)
+	}
+	r.ForwardPath = forwardPath
+
+	target, err := rewriteTarget(replacement, forwardPath)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite %w", err)
+	}
+	replacement = target
+
+	if path == "" {
+		path = location
+	}
+
+	r.TargetURL = replacement
+	r.SourceURLs = nginxSources(names, path)
+
+	return r, nginxSelfRedirect(r)
+}
+
+// nginxSelfRedirect stops a redirect from matching its own target. A source
+// without a scheme matches https too, so sources the target leads back to are
+// limited to plain http when the target uses https, as the common redirect
+// to https does; with any other scheme the redirect would loop.
+func nginxSelfRedirect(r *NginxRedirect) error {
+	target, err := url.Parse(r.TargetURL)
+	if err != nil {
+		return nil
+	}
+
+	targetPath := strings.TrimSuffix(target.Path, "/")
+
+	for i, s := range r.SourceURLs {
+		u, err := sourceURL(s)
+		if err != nil || !strings.EqualFold(u.Hostname(), target.Hostname()) {
+			continue
+		}
+
+		path := strings.TrimSuffix(u.Path, "/")
+		if targetPath != path && !(r.ForwardPath && (path == "" || strings.HasPrefix(targetPath, path+"/"))) {
+			continue
+		}
+
+		if target.Scheme != "https" {
+			return fmt.Errorf("%s redirects %s to itself", r.Directive, s)
+		}
+
+		r.SourceURLs[i] = "http://" + s
+	}
+
+	return nil
+}
+
+func nginxSources(names []string, path string) (sources []string) {
+	if path == "/" {
+		path = ""
+	}
+
+	for _, n := range names {
+		sources = append(sources, n+path)
+	}
+
+	return sources
+}
+
+// parseNginx splits the configuration into directives. Quoted strings,
+// comments and nested blocks are understood; variables and includes are left
+// as they are.
+func parseNginx(content string) ([]nginxDirective, error) {
+	tokens, err := tokenizeNginx(content)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, rest, err := parseNginxBlock(tokens, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected %q", rest[0].line, rest[0].value)
+	}
+
+	return ds, nil
+}
+
+type nginxToken struct {
+	value  string
+	line   int
+	quoted bool
+}
+
+func parseNginxBlock(tokens []nginxToken, nested bool) (ds []nginxDirective, rest []nginxToken, err error) {
+	d := nginxDirective{}
+
+	for len(tokens) > 0 {
+		t := tokens[0]
+		tokens = tokens[1:]
+
+		if t.quoted {
+			if d.Name == "" {
+				return nil, nil, fmt.Errorf("line %d: directive name cannot be quoted", t.line)
+			}
+			d.Args = append(d.Args, t.value)
+			continue
+		}
+
+		switch t.value {
+		case ";":
+			if d.Name == "" {
+				continue
+			}
+			ds = append(ds, d)
+			d = nginxDirective{}
+		case "{":
+			if d.Name == "" {
+				return nil, nil, fmt.Errorf("line %d: block without a directive", t.line)
+			}
+			d.Children, tokens, err = parseNginxBlock(tokens, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			ds = append(ds, d)
+			d = nginxDirective{}
+		case "}":
+			if !nested {
+				return nil, nil, fmt.Errorf("line %d: unexpected \"}\"", t.line)
+			}
+			if d.Name != "" {
+				return nil, nil, fmt.Errorf("line %d: directive %s is missing \";\"", d.Line, d.Name)
+			}
+			return ds, tokens, nil
+		default:
+			if d.Name == "" {
+				d.Name = t.value
+				d.Line = t.line
+				continue
+			}
+			d.Args = append(d.Args, t.value)
+		}
+	}
+
+	if nested {
+		return nil, nil, fmt.Errorf("unexpected end of file, missing \"}\"")
+	}
+	if d.Name != "" {
+		return nil, nil, fmt.Errorf("line %d: directive %s is missing \";\"", d.Line, d.Name)
+	}
+
+	return ds, tokens, nil
+}
+
+func tokenizeNginx(content string) (tokens []nginxToken, err error) {
+	line := 1
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			i--
+		case c == ';' || c == '{' || c == '}':
+			tokens = append(tokens, nginxToken{value: string(c), line: line})
+		case c == '"' || c == '\'':
+			start := line
+			var b strings.Builder
+
+			for i++; ; i++ {
+				if i >= len(content) {
+					return nil, fmt.Errorf("line %d: unterminated string", start)
+				}
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+					b.WriteByte(content[i])
+					continue
+				}
+				if content[i] == c {
+					break
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				b.WriteByte(content[i])
+			}
+
+			tokens = append(tokens, nginxToken{value: b.String(), line: start, quoted: true})
+		default:
+			start := i
+			for i < len(content) && !strings.ContainsRune(" \t\r\n;{}#", rune(content[i])) {
+				// Braces inside a word belong to it, as in ${var}.
+				if content[i] == '{' && i > start && content[i-1] == '$' {
+					for i < len(content) && content[i] != '}' {
+						i++
+					}
+				}
+				i++
+			}
+			tokens = append(tokens, nginxToken{value: content[start:i], line: line})
+			i--
+		}
+	}
+
+	return tokens, nil
+}
+
+func (rs *NginxRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file)
+
+	return rs.redirects()
+}
+
+func (rs *NginxRedirects) redirects() Redirects {
+	return configRedirects(*rs)
+}
+
+func (r *NginxRedirect) Print() {
+	printConfig(nginxPrintTemplate, r)
+}
@@ -0,0 +1,301 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		file    string
+		content string
+		options Options
+		want    []string
+	}{
+		{
+			name:   "nginx",
+			format: "nginx",
+			file:   "site.conf",
+			content: `
+server {
+  listen 80;
+  server_name old.com www.old.com;
+
+  location = /about {
+    return 301 https://new.com/about;
+  }
+
+  location /docs/ {
+    rewrite ^/docs/(.*)$ https://new.com/manual/$1 redirect;
+  }
+}
+`,
+			want: []string{
+				"old.com/about -> https://new.com/about moved_permanently path=false params=false",
+				"old.com/docs -> https://new.com/manual found path=true params=true",
+				"www.old.com/about -> https://new.com/about moved_permanently path=false params=false",
+				"www.old.com/docs -> https://new.com/manual found path=true params=true",
+			},
+		},
+		{
+			name:   "nginx server level first",
+			format: "nginx",
+			file:   "site.conf",
+			content: `
+server {
+  server_name old.com;
+
+  location /blog/ {
+    return 302 https://new.com/articles;
+  }
+
+  location /docs/ {
+    return 302 https://new.com/elsewhere;
+  }
+
+  rewrite ^/blog/(.*)$ https://$host/news/$1 permanent;
+}
+
+server {
+  server_name moved.com;
+
+  location /about {
+    return 302 https://new.com/about;
+  }
+
+  return 301 https://$server_name$request_uri;
+}
+
+server {
+  server_name a.com b.com;
+  return 301 https://$host$request_uri;
+}
+
+server {
+  server_name loop.com;
+  return 301 http://loop.com$request_uri;
+}
+`,
+			want: []string{
+				"http://moved.com -> https://moved.com moved_permanently path=true params=true",
+				"old.com/blog -> https://old.com/news moved_permanently path=true params=true",
+				"old.com/docs -> https://new.com/elsewhere found path=false params=false",
+			},
+		},
+		{
+			name:    "apache",
+			format:  "apache",
+			file:    ".htaccess",
+			options: Options{Host: "old.com"},
+			content: `
+Redirect 301 /about https://new.com/about
+RedirectMatch 302 ^/docs/(.*)$ https://new.com/manual/$1
+RewriteEngine On
+RewriteRule ^blog$ https://blog.new.com/ [R=301,L]
+`,
+			want: []string{
+				"old.com/about -> https://new.com/about moved_permanently path=true params=true",
+				"old.com/blog -> https://blog.new.com moved_permanently path=false params=true",
+				"old.com/docs -> https://new.com/manual found path=true params=true",
+			},
+		},
+		{
+			name:    "apache rewrite conditions",
+			format:  "apache",
+			file:    ".htaccess",
+			options: Options{Host: "old.com"},
+			content: `
+RewriteEngine On
+RewriteCond %{HTTP_HOST} ^old\.com$ [NC,OR]
+RewriteCond %{HTTP_HOST} ^www\.old\.com$ [NC]
+RewriteRule ^about$ https://new.com/about [R=301,L]
+
+RewriteCond %{HTTP_HOST} ^old\.com$
+RewriteCond %{HTTP_HOST} ^www\.old\.com$
+RewriteRule ^blog$ https://new.com/blog [R=301,L]
+
+RewriteCond %{HTTP_HOST} ^old\.com$ [OR]
+RewriteCond %{HTTPS} off
+RewriteRule ^docs$ https://new.com/docs [R=301,L]
+
+RewriteCond %{HTTP_HOST} ^old\.com$ [NE]
+RewriteRule ^news$ https://new.com/news [R=301,L]
+
+RewriteCond %{HTTP_HOST} ^www\.old\.com$ [OR]
+RewriteCond %{HTTP_HOST} ^old\.com$
+RewriteCond %{HTTP_HOST} ^old\.com$
+RewriteRule ^shop$ https://new.com/shop [R=302,L]
+`,
+			want: []string{
+				"old.com/about -> https://new.com/about moved_permanently path=false params=true",
+				"old.com/shop -> https://new.com/shop found path=false params=true",
+				"www.old.com/about -> https://new.com/about moved_permanently path=false params=true",
+			},
+		},
+		{
+			name:   "hiera",
+			format: "hiera",
+			file:   "common.yaml",
+			content: `
+web_redirects:
+  old:
+    host: old.com
+    aliases:
+      - www.old.com
+    redirect: https://new.com
+    extra_rewrites:
+      - ^/about$ https://new.com/about [R=301]
+`,
+			want: []string{
+				"old.com -> https://new.com moved_permanently path=true params=true",
+				"old.com/about -> https://new.com/about moved_permanently path=false params=true",
+				"www.old.com -> https://new.com moved_permanently path=true params=true",
+				"www.old.com/about -> https://new.com/about moved_permanently path=false params=true",
+			},
+		},
+		{
+			name:   "cloudflare page rules",
+			format: "cloudflare-pagerules",
+			file:   "pagerules.json",
+			content: `{"result": [
+  {"targets": [{"target": "url", "constraint": {"operator": "matches", "value": "old.com/about"}}],
+   "actions": [{"id": "forwarding_url", "value": {"url": "https://new.com/about", "status_code": 302}}], "status": "active"},
+  {"targets": [{"target": "url", "constraint": {"operator": "matches", "value": "https://old.com/docs/*"}}],
+   "actions": [{"id": "forwarding_url", "value": {"url": "https://new.com/manual/$1", "status_code": 301}}], "status": "active"},
+  {"targets": [{"target": "url", "constraint": {"operator": "matches", "value": "old.com/off"}}],
+   "actions": [{"id": "forwarding_url", "value": {"url": "https://new.com/off", "status_code": 301}}], "status": "disabled"},
+  {"targets": [{"target": "url", "constraint": {"operator": "matches", "value": "*old.com/*"}}],
+   "actions": [{"id": "forwarding_url", "value": {"url": "https://new.com/$2", "status_code": 301}}], "status": "active"},
+  {"targets": [{"target": "url", "constraint": {"operator": "matches", "value": "old.com/cache"}}],
+   "actions": [{"id": "cache_level", "value": "bypass"}], "status": "active"}
+]}`,
+			want: []string{
+				"https://old.com/docs -> https://new.com/manual moved_permanently path=true params=true",
+				"old.com/about -> https://new.com/about found path=false params=true",
+			},
+		},
+		{
+			name:   "puppet",
+			format: "puppet",
+			file:   "site.pp",
+			content: `
+# Redirects served by the controllers.
+$target = 'https://new.com'
+$controllers = {
+  'redirects' => {
+    'old' => {
+      'apache_hosts'    => ['old.com', 'www.old.com'],
+      'apache_redirect' => "${target}/landing",
+    },
+    'single' => {
+      apache_hosts    => 'single.com',
+      apache_redirect => $target,
+    },
+  },
+}
+`,
+			want: []string{
+				"old.com -> https://new.com/landing moved_permanently path=false params=false",
+				"single.com -> https://new.com moved_permanently path=false params=false",
+				"www.old.com -> https://new.com/landing moved_permanently path=false params=false",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeFile(t, tt.file, tt.content)
+
+			got := ruleSummary(formats[tt.format].New().Parse(file, &tt.options), false)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHieraParseRewrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite string
+		want    bool
+	}{
+		{name: "pattern and target", rewrite: "^/a$ https://new.com/a", want: true},
+		{name: "flags", rewrite: "^/a$ https://new.com/a [R=301,L]", want: true},
+		{name: "missing target", rewrite: "^/a$"},
+		{name: "extra field", rewrite: "^/a$ https://new.com/a [R=301] [L]"},
+		{name: "unknown flag", rewrite: "^/a$ https://new.com/a [R=301,PT]"},
+		{name: "invalid redirect status", rewrite: "^/a$ https://new.com/a [R=often]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := HieraRedirect{Name: "test", ExtraRewrites: []string{tt.rewrite}}
+			r.parseRewrites()
+
+			if got := len(r.RewriteRules) == 1; got != tt.want {
+				t.Errorf("parsed = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHieraLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file func(t *testing.T) string
+	}{
+		{name: "missing file", file: func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.yaml") }},
+		{name: "invalid yaml", file: func(t *testing.T) string { return writeFile(t, "common.yaml", "web_redirects: [") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := HieraRedirects{}
+			if err := rs.Load(tt.file(t)); err == nil {
+				t.Errorf("Load() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPrintTemplates(t *testing.T) {
+	redirect := simpleRedirect{
+		SourceURLs:    []string{"old.com/a"},
+		TargetURL:     "https://new.com/a",
+		ForwardParams: true,
+		ResponseType:  "moved_permanently",
+	}
+
+	tests := []struct {
+		name string
+		tmpl string
+		v    interface{}
+	}{
+		{name: "apache", tmpl: apachePrintTemplate, v: &ApacheRedirect{simpleRedirect: redirect}},
+		{name: "cloudflare", tmpl: cloudflarePrintTemplate, v: &CloudflareRedirect{simpleRedirect: redirect}},
+		{name: "hiera rewrite", tmpl: hieraRewritePrintTemplate, v: &HieraRewriteRedirect{simpleRedirect: redirect}},
+		{name: "netlify", tmpl: netlifyPrintTemplate, v: &NetlifyRedirect{simpleRedirect: redirect}},
+		{name: "nginx", tmpl: nginxPrintTemplate, v: &NginxRedirect{simpleRedirect: redirect}},
+		{name: "vercel", tmpl: vercelPrintTemplate, v: &VercelRedirect{simpleRedirect: redirect}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w strings.Builder
+			if err := template.Must(template.New("").Parse(tt.tmpl)).Execute(&w, tt.v); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+
+			if !strings.Contains(w.String(), "Target URL: https://new.com/a") {
+				t.Errorf("output = %q, want the target URL", w.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/rs/zerolog/log"
+
+	_ "embed"
+)
+
+//go:embed plan_print.tmpl
+var planPrintTemplate string
+
+var planFuncs = template.FuncMap{
+	"symbol": planSymbol,
+	"change": planChange,
+}
+
+func (rs *YAMLRedirects) Plan(ctx context.Context, c *easyredir.Client, prune bool) {
+	changes, err := rs.Changes(ctx, c)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	if !prune {
+		changes.keepUnmanaged()
+	}
+
+	changes.Print()
+
+	return
+}
+
+// Count returns the number of rule and host operations for an action.
+func (cs *Changes) Count(action string) (n int) {
+	for _, rc := range cs.Rules {
+		if rc.Action == action {
+			n++
+		}
+	}
+	for _, hc := range cs.Hosts {
+		if hc.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (cs *Changes) Print() {
+	fmt.Printf("%s:\n", text.FgGreen.Sprint("PLAN"))
+	fmt.Println()
+
+	if len(cs.Rules) == 0 && len(cs.Hosts) == 0 {
+		fmt.Println("No changes. The account matches the spec.")
+		fmt.Println()
+		return
+	}
+
+	var w bytes.Buffer
+
+	t := template.Must(template.New("").Funcs(planFuncs).Parse(planPrintTemplate))
+	t.Execute(&w, cs)
+
+	quick.Highlight(os.Stdout, strings.TrimPrefix(w.String(), "\n"), "diff", "terminal256", "pygments")
+
+	fmt.Println()
+	fmt.Printf("Plan: %d to create, %d to update, %d to delete.\n", cs.Count(ActionCreate), cs.Count(ActionUpdate), cs.Count(ActionDelete))
+	fmt.Println()
+
+	return
+}
+
+func planSymbol(action string) string {
+	switch action {
+	case ActionCreate:
+		return "+"
+	case ActionDelete:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func planChange(action string, ac AttributeChange) string {
+	switch action {
+	case ActionCreate:
+		return strconv.Quote(ac.After)
+	case ActionDelete:
+		return strconv.Quote(ac.Before)
+	default:
+		return fmt.Sprintf("%s -> %s", strconv.Quote(ac.Before), strconv.Quote(ac.After))
+	}
+}
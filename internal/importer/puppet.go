@@ -1,68 +1,110 @@
 package importer
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"os"
-	"regexp"
-	"text/template"
-
-	"github.com/tailscale/hujson"
+	"strings"
 
 	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
 
-	"github.com/alecthomas/chroma/quick"
-	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/rs/zerolog/log"
 
 	_ "embed"
 )
 
 const (
-	manifestStart string = "$controllers = "
+	defaultPuppetPath string = "$controllers.redirects"
 )
 
 //go:embed puppet_print.tmpl
 var puppetPrintTemplate string
 
+func init() {
+	Register(Format{
+		Name: "puppet",
+		New:  func() Importer { return &PuppetRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".pp")
+		},
+	})
+}
+
 type PuppetRedirects []PuppetRedirect
 
 type PuppetRedirect struct {
 	Name          string
-	SourceURLs    []string `json:"apache_hosts"`
-	TargetURL     string   `json:"apache_redirect"`
+	SourceURLs    []string
+	TargetURL     string
 	ForwardParams *bool
 	ForwardPath   *bool
 	ResponseType  *string
 }
 
-func (rs *PuppetRedirects) Load(file string) {
+// Load reads the redirects hash found at path, a variable followed by the
+// keys leading to the hash, such as $controllers.redirects. Each entry names
+// a redirect with its apache_hosts and apache_redirect.
+func (rs *PuppetRedirects) Load(file string, path string) {
 	content, err := ioutil.ReadFile(file)
 	if err != nil {
 		log.Error().Err(err).Msg("")
+		return
+	}
+
+	if path == "" {
+		path = defaultPuppetPath
+	}
+	keys := strings.Split(path, ".")
+
+	v, err := parsePuppet(string(content), keys[0])
+	if err == nil {
+		v, err = v.lookup(keys[1:])
+	}
+	if err == nil && v.Kind != puppetHash {
+		err = fmt.Errorf("line %d: %s is not a hash", v.Line, path)
+	}
+	if err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
 	}
 
-	f := bytes.NewReader(content)
-	startOffset := scanFile(f, []byte(manifestStart)) + int64(len(manifestStart))
-	endOffset := scanBracket(f, startOffset)
+	for _, name := range v.Keys {
+		r, err := puppetRedirect(name, v.Hash[name])
+		if err != nil {
+			log.Warn().Msgf("%s: redirect %s: %s", file, name, err)
+			continue
+		}
 
-	block := getBlock(file, int(startOffset), int(endOffset))
-	res := convertPuppet(block)
+		*rs = append(*rs, *r)
+	}
 
-	rawRedirects := make(map[string]map[string]PuppetRedirect)
+	return
+}
 
-	err = hujson.Unmarshal([]byte(res), &rawRedirects)
+func puppetRedirect(name string, v puppetValue) (*PuppetRedirect, error) {
+	if v.Kind != puppetHash {
+		return nil, fmt.Errorf("line %d: expected a hash", v.Line)
+	}
+
+	r := &PuppetRedirect{Name: name}
+
+	hosts, ok := v.Hash["apache_hosts"]
+	if !ok {
+		return nil, fmt.Errorf("line %d: missing apache_hosts", v.Line)
+	}
+
+	sources, err := hosts.strings()
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
+	r.SourceURLs = sources
 
-	for k, v := range rawRedirects["redirects"] {
-		v.Name = k
-		*rs = append(*rs, v)
+	target, ok := v.Hash["apache_redirect"]
+	if !ok || target.Kind != puppetString {
+		return nil, fmt.Errorf("line %d: apache_redirect must be a string", v.Line)
 	}
+	r.TargetURL = target.String
+
+	return r, nil
 }
 
 func (rs *PuppetRedirects) Defaults() {
@@ -95,130 +137,30 @@ func (rs *PuppetRedirects) Defaults() {
 	return
 }
 
-func (rs *PuppetRedirects) Import(preview bool) {
-	c, err := easyredir.NewClient()
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return
-	}
-
-	for _, r := range *rs {
-		r.Print()
-
-		if preview == true {
-			continue
-		}
-
-		rule := easyredir.Rule{}
-		rule.Data.Attributes.ForwardParams = *r.ForwardParams
-		rule.Data.Attributes.ForwardPath = *r.ForwardPath
-		rule.Data.Attributes.ResponseType = *r.ResponseType
-
-		for _, v := range r.SourceURLs {
-			rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, v)
-		}
-		rule.Data.Attributes.TargetURL = r.TargetURL
-
-		res, err := c.CreateRule(&rule)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			continue
-		}
+func (rs *PuppetRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file, options.Path)
+	rs.Defaults()
 
-		res.Print()
-	}
+	return rs.redirects()
 }
 
-func (r *PuppetRedirect) Print() {
-	fmt.Printf("%s:\n", text.FgCyan.Sprint("CONFIG"))
-	fmt.Println()
-
-	var w bytes.Buffer
-
-	t := template.Must(template.New("").Parse(puppetPrintTemplate))
-	t.Execute(&w, r)
-
-	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
-
-	fmt.Println()
-
-	return
+func (rs *PuppetRedirects) redirects() Redirects {
+	return configRedirects(*rs)
 }
 
-func scanFile(f io.ReadSeeker, search []byte) int64 {
-	ix := 0
-	r := bufio.NewReader(f)
-	offset := int64(0)
-	for ix < len(search) {
-		b, err := r.ReadByte()
-		if err != nil {
-			return -1
-		}
-		if search[ix] == b {
-			ix++
-		} else {
-			ix = 0
-		}
-		offset++
-	}
-	return offset - int64(len(search))
-}
+func (r *PuppetRedirect) rule() (rule easyredir.Rule) {
+	rule.Data.Attributes.ForwardParams = *r.ForwardParams
+	rule.Data.Attributes.ForwardPath = *r.ForwardPath
+	rule.Data.Attributes.ResponseType = *r.ResponseType
 
-func scanBracket(f io.ReadSeeker, offset int64) int64 {
-	ix := 0
-	// bracket match depth is 0
-	bm := false
-	// found matching close bracket
-	cb := false
-	r := bufio.NewReader(f)
-	f.Seek(offset, 0)
-	for !(bm && cb) {
-		b, err := r.ReadByte()
-		if err != nil {
-			return -1
-		}
-		if '{' == b {
-			ix++
-			cb = false
-		}
-		if '}' == b {
-			ix--
-			cb = true
-		}
-		if ix == 0 {
-			bm = true
-		}
-		if ix != 0 {
-			bm = false
-		}
-		offset++
+	for _, v := range r.SourceURLs {
+		rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, v)
 	}
-	return offset
-}
-
-func convertPuppet(content []byte) []byte {
-	reRocket := regexp.MustCompile(`([1-9a-z_]+).*=>`)
-	removedRocket := reRocket.ReplaceAll(content, []byte("\"$1\":"))
-
-	reSingleQuote := regexp.MustCompile(`'`)
-	removedQuote := reSingleQuote.ReplaceAll(removedRocket, []byte("\""))
+	rule.Data.Attributes.TargetURL = r.TargetURL
 
-	return removedQuote
+	return rule
 }
 
-func getBlock(filename string, startOffset int, endOffset int) []byte {
-	fd, err := os.Open(filename)
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return nil
-	}
-
-	reader := bufio.NewReaderSize(fd, endOffset-startOffset)
-	_, _ = reader.Discard(startOffset)
-	block, err := reader.Peek(endOffset - startOffset)
-	if err != nil {
-		fmt.Println(err)
-	}
-
-	return block
+func (r *PuppetRedirect) Print() {
+	printConfig(puppetPrintTemplate, r)
 }
@@ -0,0 +1,490 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	puppetTokenVariable string = "variable"
+	puppetTokenString   string = "string"
+	puppetTokenBareword string = "bareword"
+	puppetTokenPunct    string = "punct"
+)
+
+const (
+	puppetString string = "string"
+	puppetArray  string = "array"
+	puppetHash   string = "hash"
+	puppetUndef  string = "undef"
+)
+
+type puppetToken struct {
+	kind  string
+	value string
+	line  int
+	// parts holds the literal text and variable names of a double quoted
+	// string, with variables at odd indexes.
+	parts []string
+}
+
+// puppetValue is a literal from a manifest. Numbers and booleans are kept as
+// strings; hashes keep the order of their keys.
+type puppetValue struct {
+	Kind   string
+	Line   int
+	String string
+	Array  []puppetValue
+	Keys   []string
+	Hash   map[string]puppetValue
+}
+
+// puppetParser reads the variable assignments of a manifest. Only literal
+// values are understood; everything else in the manifest is skipped.
+type puppetParser struct {
+	tokens    []puppetToken
+	pos       int
+	variables map[string]puppetValue
+}
+
+// parsePuppet finds the assignment of variable and returns its value. Earlier
+// assignments of literals can be used as values and in double quoted strings.
+func parsePuppet(content string, variable string) (*puppetValue, error) {
+	tokens, err := tokenizePuppet(content)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &puppetParser{tokens: tokens, variables: make(map[string]puppetValue)}
+	variable = puppetVariableName(variable)
+
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		p.pos++
+
+		if t.kind != puppetTokenVariable || !p.accept("=") {
+			continue
+		}
+
+		name := puppetVariableName(t.value)
+		start := p.pos
+
+		v, err := p.value()
+		if name == variable {
+			if err != nil {
+				return nil, err
+			}
+			return &v, nil
+		}
+
+		// Other assignments may be expressions this parser does not follow.
+		if err != nil {
+			p.pos = start
+			continue
+		}
+		p.variables[name] = v
+	}
+
+	return nil, fmt.Errorf("variable $%s is not assigned", variable)
+}
+
+func (p *puppetParser) accept(punct string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == puppetTokenPunct && p.tokens[p.pos].value == punct {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *puppetParser) value() (puppetValue, error) {
+	if p.pos >= len(p.tokens) {
+		return puppetValue{}, fmt.Errorf("unexpected end of file")
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case puppetTokenString:
+		s, err := p.interpolate(t)
+		if err != nil {
+			return puppetValue{}, err
+		}
+		return puppetValue{Kind: puppetString, Line: t.line, String: s}, nil
+	case puppetTokenVariable:
+		v, ok := p.variables[puppetVariableName(t.value)]
+		if !ok {
+			return puppetValue{}, fmt.Errorf("line %d: variable %s is not assigned a literal", t.line, t.value)
+		}
+		v.Line = t.line
+		return v, nil
+	case puppetTokenBareword:
+		if p.pos < len(p.tokens) && p.tokens[p.pos].value == "(" {
+			return puppetValue{}, fmt.Errorf("line %d: function call %s cannot be read", t.line, t.value)
+		}
+		if t.value == "undef" {
+			return puppetValue{Kind: puppetUndef, Line: t.line}, nil
+		}
+		return puppetValue{Kind: puppetString, Line: t.line, String: t.value}, nil
+	}
+
+	switch t.value {
+	case "-":
+		if p.pos < len(p.tokens) && p.tokens[p.pos].kind == puppetTokenBareword {
+			n := p.tokens[p.pos]
+			p.pos++
+			return puppetValue{Kind: puppetString, Line: t.line, String: "-" + n.value}, nil
+		}
+	case "[":
+		v := puppetValue{Kind: puppetArray, Line: t.line}
+		for !p.accept("]") {
+			e, err := p.value()
+			if err != nil {
+				return puppetValue{}, err
+			}
+			v.Array = append(v.Array, e)
+
+			if !p.accept(",") && !p.peek("]") {
+				return puppetValue{}, p.unexpected("',' or ']'")
+			}
+		}
+		return v, nil
+	case "{":
+		v := puppetValue{Kind: puppetHash, Line: t.line, Hash: make(map[string]puppetValue)}
+		for !p.accept("}") {
+			k, err := p.value()
+			if err != nil {
+				return puppetValue{}, err
+			}
+			if k.Kind != puppetString {
+				return puppetValue{}, fmt.Errorf("line %d: hash keys must be strings", k.Line)
+			}
+
+			if !p.accept("=>") {
+				return puppetValue{}, p.unexpected("'=>'")
+			}
+
+			e, err := p.value()
+			if err != nil {
+				return puppetValue{}, err
+			}
+
+			if _, dup := v.Hash[k.String]; dup {
+				return puppetValue{}, fmt.Errorf("line %d: duplicate key %q", k.Line, k.String)
+			}
+			v.Keys = append(v.Keys, k.String)
+			v.Hash[k.String] = e
+
+			if !p.accept(",") && !p.peek("}") {
+				return puppetValue{}, p.unexpected("',' or '}'")
+			}
+		}
+		return v, nil
+	}
+
+	p.pos--
+
+	return puppetValue{}, p.unexpected("a value")
+}
+
+func (p *puppetParser) peek(punct string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == puppetTokenPunct && p.tokens[p.pos].value == punct
+}
+
+func (p *puppetParser) unexpected(want string) error {
+	if p.pos >= len(p.tokens) {
+		return fmt.Errorf("unexpected end of file, expected %s", want)
+	}
+
+	t := p.tokens[p.pos]
+
+	return fmt.Errorf("line %d: unexpected %q, expected %s", t.line, t.value, want)
+}
+
+// interpolate replaces the variables of a double quoted string with earlier
+// string assignments.
+func (p *puppetParser) interpolate(t puppetToken) (string, error) {
+	if t.parts == nil {
+		return t.value, nil
+	}
+
+	var b strings.Builder
+
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			b.WriteString(part)
+			continue
+		}
+
+		v, ok := p.variables[puppetVariableName(part)]
+		if !ok || v.Kind != puppetString {
+			return "", fmt.Errorf("line %d: variable $%s in string is not assigned a string", t.line, part)
+		}
+		b.WriteString(v.String)
+	}
+
+	return b.String(), nil
+}
+
+// puppetVariableName drops the sigil and top scope prefix, so $::name and
+// $name are the same variable.
+func puppetVariableName(name string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(name, "$"), "::")
+}
+
+func tokenizePuppet(content string) (tokens []puppetToken, err error) {
+	line := 1
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			start := line
+			end := strings.Index(content[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			line += strings.Count(content[i:i+2+end], "\n")
+			i += end + 3
+		case c == '/' && puppetRegexAllowed(tokens):
+			start := line
+			for i++; i < len(content) && content[i] != '/'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+				if i >= len(content) || content[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated regular expression", start)
+				}
+			}
+			if i >= len(content) {
+				return nil, fmt.Errorf("line %d: unterminated regular expression", start)
+			}
+		case c == '\'':
+			start := line
+			var b strings.Builder
+
+			for i++; ; i++ {
+				if i >= len(content) {
+					return nil, fmt.Errorf("line %d: unterminated string", start)
+				}
+				if content[i] == '\\' && i+1 < len(content) && (content[i+1] == '\'' || content[i+1] == '\\') {
+					i++
+					b.WriteByte(content[i])
+					continue
+				}
+				if content[i] == '\'' {
+					break
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				b.WriteByte(content[i])
+			}
+
+			tokens = append(tokens, puppetToken{kind: puppetTokenString, value: b.String(), line: start})
+		case c == '"':
+			t, n, err := tokenizePuppetString(content[i:], line)
+			if err != nil {
+				return nil, err
+			}
+
+			line += strings.Count(content[i:i+n], "\n")
+			i += n - 1
+
+			tokens = append(tokens, t)
+		case c == '$':
+			start := i
+			for i++; i < len(content) && (puppetNameChar(content[i]) || content[i] == ':'); i++ {
+			}
+			tokens = append(tokens, puppetToken{kind: puppetTokenVariable, value: content[start:i], line: line})
+			i--
+		case puppetNameChar(c):
+			start := i
+			for ; i < len(content) && (puppetNameChar(content[i]) || content[i] == ':' || content[i] == '.' || content[i] == '-'); i++ {
+			}
+			tokens = append(tokens, puppetToken{kind: puppetTokenBareword, value: content[start:i], line: line})
+			i--
+		default:
+			// Two character operators, so => is not read as = and >.
+			if i+1 < len(content) {
+				switch op := content[i : i+2]; op {
+				case "=>", "==", "!=", "=~", "!~", "<=", ">=", "->", "~>", "<-", "<~", "+>":
+					tokens = append(tokens, puppetToken{kind: puppetTokenPunct, value: op, line: line})
+					i++
+					continue
+				}
+			}
+			tokens = append(tokens, puppetToken{kind: puppetTokenPunct, value: string(c), line: line})
+		}
+	}
+
+	return tokens, nil
+}
+
+// tokenizePuppetString reads a double quoted string at the start of s and
+// returns the token and the number of bytes read.
+func tokenizePuppetString(s string, line int) (t puppetToken, n int, err error) {
+	t = puppetToken{kind: puppetTokenString, line: line}
+
+	var b strings.Builder
+	interpolated := false
+
+	for i := 1; ; i++ {
+		if i >= len(s) {
+			return t, 0, fmt.Errorf("line %d: unterminated string", line)
+		}
+
+		switch c := s[i]; c {
+		case '"':
+			t.value = b.String()
+			if interpolated {
+				t.parts = append(t.parts, b.String())
+			}
+			return t, i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 's':
+				b.WriteByte(' ')
+			case '"', '\\', '$', '\'':
+				b.WriteByte(s[i])
+			case '\n':
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case '$':
+			name := ""
+
+			switch {
+			case i+1 < len(s) && s[i+1] == '{':
+				end := strings.IndexByte(s[i:], '}')
+				if end == -1 {
+					return t, 0, fmt.Errorf("line %d: unterminated interpolation", line)
+				}
+				name = strings.TrimSpace(s[i+2 : i+end])
+				if !puppetSimpleName(name) {
+					return t, 0, fmt.Errorf("line %d: interpolation ${%s} cannot be read", line, name)
+				}
+				i += end
+			case i+1 < len(s) && (puppetNameChar(s[i+1]) || strings.HasPrefix(s[i+1:], "::")):
+				j := i + 1
+				for j < len(s) {
+					if strings.HasPrefix(s[j:], "::") {
+						j += 2
+						continue
+					}
+					if !puppetNameChar(s[j]) {
+						break
+					}
+					j++
+				}
+				name = s[i+1 : j]
+				i = j - 1
+			default:
+				b.WriteByte('$')
+				continue
+			}
+
+			interpolated = true
+			t.parts = append(t.parts, b.String(), name)
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// puppetRegexAllowed reports whether a slash starts a regular expression,
+// which only follows a match operator or opens a node or case option.
+func puppetRegexAllowed(tokens []puppetToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch t := tokens[len(tokens)-1]; t.value {
+	case "=~", "!~", "node", "{", ",":
+		return t.kind != puppetTokenString
+	}
+
+	return false
+}
+
+func puppetSimpleName(name string) bool {
+	name = strings.TrimPrefix(name, "::")
+	if name == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "::") {
+		if part == "" {
+			return false
+		}
+		for i := 0; i < len(part); i++ {
+			if !puppetNameChar(part[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func puppetNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lookup follows keys from v, reporting the first key that is missing.
+func (v *puppetValue) lookup(keys []string) (*puppetValue, error) {
+	for _, k := range keys {
+		if v.Kind != puppetHash {
+			return nil, fmt.Errorf("line %d: value is not a hash, cannot look up %q", v.Line, k)
+		}
+
+		e, ok := v.Hash[k]
+		if !ok {
+			return nil, fmt.Errorf("line %d: hash has no key %q", v.Line, k)
+		}
+		v = &e
+	}
+
+	return v, nil
+}
+
+// strings returns a string or an array of strings as a list.
+func (v *puppetValue) strings() ([]string, error) {
+	switch v.Kind {
+	case puppetString:
+		return []string{v.String}, nil
+	case puppetArray:
+		list := []string{}
+		for _, e := range v.Array {
+			if e.Kind != puppetString {
+				return nil, fmt.Errorf("line %d: expected a string", e.Line)
+			}
+			list = append(list, e.String)
+		}
+		return list, nil
+	}
+
+	return nil, fmt.Errorf("line %d: expected a string or an array of strings", v.Line)
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/rs/zerolog/log"
+)
+
+// simpleRedirect holds the rule settings of formats that convert straight
+// into them. Format redirect types embed it next to their own fields.
+type simpleRedirect struct {
+	SourceURLs    []string
+	TargetURL     string
+	ForwardParams bool
+	ForwardPath   bool
+	ResponseType  string
+}
+
+func (r *simpleRedirect) rule() (rule easyredir.Rule) {
+	rule.Data.Attributes.ForwardParams = r.ForwardParams
+	rule.Data.Attributes.ForwardPath = r.ForwardPath
+	rule.Data.Attributes.ResponseType = r.ResponseType
+	rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, r.SourceURLs...)
+	rule.Data.Attributes.TargetURL = r.TargetURL
+
+	return rule
+}
+
+// configRedirects builds a rule from each redirect, keeping the redirect as
+// its config.
+func configRedirects[T any, P interface {
+	*T
+	Print()
+	rule() easyredir.Rule
+}](rs []T) (out Redirects) {
+	for i := range rs {
+		r := P(&rs[i])
+		out.Redirects = append(out.Redirects, Redirect{Rule: r.rule(), Config: r})
+	}
+
+	return out
+}
+
+// printConfig prints a redirect as it was read, using its format's template.
+func printConfig(tmpl string, v interface{}) {
+	fmt.Printf("%s:\n", text.FgCyan.Sprint("CONFIG"))
+	fmt.Println()
+
+	var w bytes.Buffer
+
+	t := template.Must(template.New("").Parse(tmpl))
+	if err := t.Execute(&w, v); err != nil {
+		log.Error().Err(fmt.Errorf("printConfig: %w", err)).Msg("")
+		return
+	}
+
+	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
+
+	fmt.Println()
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Importer reads a file in one format into the normalized model. Problems
+// with single redirects are logged and the redirect left out.
+type Importer interface {
+	Parse(file string, options *Options) Redirects
+}
+
+// ImporterFunc adapts a function to the Importer interface, for formats that
+// share a redirect type with another format.
+type ImporterFunc func(file string, options *Options) Redirects
+
+func (f ImporterFunc) Parse(file string, options *Options) Redirects {
+	return f(file, options)
+}
+
+// Format is an import format. Detect reports whether a file is in the format
+// from its name and content; detectors should not overlap.
+type Format struct {
+	Name   string
+	New    func() Importer
+	Detect func(file string, content []byte) bool
+}
+
+var formats = make(map[string]Format)
+
+// Register makes a format available to Import. Formats register themselves
+// from init.
+func Register(f Format) {
+	if _, dup := formats[f.Name]; dup {
+		panic(fmt.Sprintf("importer: format %s registered twice", f.Name))
+	}
+
+	formats[f.Name] = f
+}
+
+// Formats returns the names of the registered formats in order.
+func Formats() []string {
+	names := []string{}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Detect picks the format of a file from its name and content.
+func Detect(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("Detect: %w", err)
+	}
+
+	matches := []string{}
+	for _, name := range Formats() {
+		if d := formats[name].Detect; d != nil && d(file, content) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Detect: unable to detect the format of %s, set the format", file)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("Detect: %s could be any of %s, set the format", file, strings.Join(matches, ", "))
+	}
+}
+
+// hasExtension reports whether the file ends with one of the extensions,
+// ignoring case.
+func hasExtension(file string, extensions ...string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+// Resolve simulates the response to a URL using the rules in the spec file
+// when one is given, or the live account otherwise.
+func Resolve(ctx context.Context, options *Options, rawURL string) (*easyredir.Resolution, error) {
+	var s *easyredir.Simulator
+
+	if options.File != "" {
+		r, err := loadSpec(options.File)
+		if err != nil {
+			return nil, fmt.Errorf("Resolve: %w", err)
+		}
+
+		s = r.Simulator()
+	} else {
+		var err error
+
+		s, err = options.Client.Simulator(ctx, sourceHost(rawURL))
+		if err != nil {
+			return nil, fmt.Errorf("Resolve: %w", err)
+		}
+	}
+
+	res, err := s.Resolve(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("Resolve: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Chains reports redirect chains and loops across every rule in the spec file
+// when one is given, or the live account otherwise.
+func Chains(ctx context.Context, options *Options) (easyredir.Chains, error) {
+	var s *easyredir.Simulator
+
+	if options.File != "" {
+		r, err := loadSpec(options.File)
+		if err != nil {
+			return nil, fmt.Errorf("Chains: %w", err)
+		}
+
+		s = r.Simulator()
+	} else {
+		var err error
+
+		s, err = options.Client.Simulator(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Chains: %w", err)
+		}
+	}
+
+	return s.Chains(), nil
+}
+
+// Simulator builds the rules and hosts described by the spec. Host settings
+// come from the first source naming the host, matching how Apply configures
+// them.
+func (rs *YAMLRedirects) Simulator() *easyredir.Simulator {
+	s := &easyredir.Simulator{}
+	seen := make(map[string]bool)
+
+	for _, r := range *rs {
+		s.Rules = append(s.Rules, r.rule())
+
+		for _, src := range r.Sources {
+			if src.URL == nil {
+				continue
+			}
+
+			name := strings.ToLower(sourceHost(*src.URL))
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			host := easyredir.Host{}
+			host.Data.Attributes.Name = name
+			applySourceOptions(&host, src.Options.withDefaults())
+
+			s.Hosts = append(s.Hosts, host)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type HieraRewriteRules []HieraRewriteRule
+
+// HieraRewriteRule is a mod_rewrite RewriteRule. Hiera extra_rewrites and
+// Apache configuration share it.
+type HieraRewriteRule struct {
+	Pattern string
+	Target  string
+	Flags   HieraRewriteRuleFlags
+}
+
+type HieraRewriteRuleFlags struct {
+	End                bool // [END]
+	Forbidden          bool // [F]
+	Gone               bool // [G]
+	Last               bool // [L]
+	NoCase             bool // [NC]
+	NoEscape           bool // [NE]
+	QueryStringAppend  bool // [QSA]
+	QueryStringDiscard bool // [QSD]
+	Redirect           int  // [R=x]
+	Unknown            []string
+}
+
+var (
+	// rewriteLiteral matches a regular expression path with no special
+	// characters other than escaped dots and dashes.
+	rewriteLiteral = `/?(?:[^\\^$.*+?()\[\]{}|]|\\[.\-/])*`
+
+	// rewriteExact matches patterns for a single path, such as ^/old$.
+	rewriteExact = regexp.MustCompile(`^\^(` + rewriteLiteral + `)\$$`)
+
+	// rewritePrefix matches patterns that capture everything after a path,
+	// such as ^/old/(.*)$.
+	rewritePrefix = regexp.MustCompile(`^\^(` + rewriteLiteral + `)\(\.\*\)\$?$`)
+)
+
+// parseFlags reads a mod_rewrite flag list such as [L,R=301,QSD]. Flags that
+// are not understood are kept in Unknown and reported in the error.
+func (flags *HieraRewriteRuleFlags) parseFlags(f string) error {
+	ft := strings.Trim(f, "[]")
+	fs := strings.Split(ft, ",")
+	for _, v := range fs {
+		name, value, _ := strings.Cut(strings.TrimSpace(v), "=")
+
+		switch strings.ToLower(name) {
+		case "r", "redirect":
+			code, err := redirectStatus(value)
+			if err != nil {
+				flags.Unknown = append(flags.Unknown, v)
+				continue
+			}
+			flags.Redirect = code
+		case "l", "last":
+			flags.Last = true
+		case "end":
+			flags.End = true
+		case "nc", "nocase":
+			flags.NoCase = true
+		case "qsa", "qsappend":
+			flags.QueryStringAppend = true
+		case "qsd", "qsdiscard":
+			flags.QueryStringDiscard = true
+		case "ne", "noescape":
+			flags.NoEscape = true
+		case "f", "forbidden":
+			flags.Forbidden = true
+		case "g", "gone":
+			flags.Gone = true
+		case "":
+		default:
+			flags.Unknown = append(flags.Unknown, v)
+		}
+	}
+
+	if len(flags.Unknown) > 0 {
+		return fmt.Errorf("unknown rewrite flags %s", strings.Join(flags.Unknown, ","))
+	}
+
+	return nil
+}
+
+// redirectStatus reads the status of an R flag or Redirect directive, which
+// is a number or one of the mod_alias keywords. An empty status is a
+// temporary redirect.
+func redirectStatus(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 302, nil
+	case "permanent":
+		return 301, nil
+	case "temp":
+		return 302, nil
+	case "seeother":
+		return 303, nil
+	case "gone":
+		return 410, nil
+	}
+
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid redirect status %s", s)
+	}
+
+	return code, nil
+}
+
+// rewritePath converts a rewrite pattern into the path a source URL matches.
+// Patterns for an exact path, a path prefix capturing the rest, and the
+// whole path are understood. Prefix and whole path patterns forward the
+// captured path, which the target must end with as $1.
+func rewritePath(pattern string) (path string, forwardPath bool, ok bool) {
+	switch {
+	case pattern == "^(.*)$" || pattern == "^/(.*)$" || pattern == "^/?(.*)$" || pattern == "(.*)":
+		return "", true, true
+	case rewriteExact.MatchString(pattern):
+		path = rewriteExact.FindStringSubmatch(pattern)[1]
+	case rewritePrefix.MatchString(pattern):
+		path = rewritePrefix.FindStringSubmatch(pattern)[1]
+		forwardPath = true
+	default:
+		return "", false, false
+	}
+
+	path = strings.NewReplacer(`\.`, ".", `\-`, "-", `\/`, "/").Replace(path)
+
+	// Per-directory rules match without the leading slash.
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return path, forwardPath, true
+}
+
+// rewriteTarget strips the captured path reference from a rewrite target when
+// the path is forwarded. Targets still using variables cannot be converted.
+func rewriteTarget(target string, forwardPath bool) (string, error) {
+	if forwardPath {
+		if !strings.HasSuffix(target, "$1") {
+			return "", fmt.Errorf("target %s does not forward the captured path", target)
+		}
+		target = strings.TrimSuffix(target, "$1")
+	}
+
+	if strings.Contains(target, "$") || strings.Contains(target, "%{") {
+		return "", fmt.Errorf("target %s uses variables that cannot be converted", target)
+	}
+
+	if !strings.Contains(target, "://") {
+		return "", fmt.Errorf("target %s is not an absolute URL", target)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,276 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+const (
+	terraformProviderSource string = "mikelorant/easyredir"
+	terraformRuleType       string = "easyredir_rule"
+	terraformHostType       string = "easyredir_host"
+)
+
+func init() {
+	RegisterExport(ExportFormat{Name: "terraform", Export: exportTerraform})
+}
+
+// terraformInvalid matches characters that cannot appear in a resource name.
+var terraformInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// hclBlock is a block of attributes and nested blocks. Attributes are written
+// in order with their equals signs aligned, as terraform fmt does.
+type hclBlock struct {
+	Header     string
+	Attributes [][2]string
+	Blocks     []hclBlock
+}
+
+// exportTerraform writes a resource for every host and rule in the account,
+// followed by import blocks that adopt them by ID. The resources follow the
+// schema of the provider named in the terraform block:
+//
+//	easyredir_rule: source_urls, target_url, response_type, forward_params
+//	and forward_path, as in the API.
+//	easyredir_host: name with match_options, not_found_action and security
+//	blocks holding the host settings under their API names.
+func exportTerraform(ctx context.Context, c *easyredir.Client, w io.Writer) error {
+	hosts, err := c.ListHosts(ctx, &easyredir.HostsOptions{})
+	if err != nil {
+		return fmt.Errorf("exportTerraform: %w", err)
+	}
+
+	rules, err := c.ListRules(ctx, &easyredir.RulesOptions{})
+	if err != nil {
+		return fmt.Errorf("exportTerraform: %w", err)
+	}
+
+	blocks := []hclBlock{terraformProvider()}
+	imports := []hclBlock{}
+	names := make(map[string]int)
+
+	for _, h := range hosts.Data {
+		host := easyredir.Host{}
+		host.Data.ID = h.ID
+
+		if err = c.GetHost(ctx, &host); err != nil {
+			return fmt.Errorf("exportTerraform: %w", err)
+		}
+
+		name := terraformName(names, "host_"+host.Data.Attributes.Name)
+		blocks = append(blocks, terraformHost(&host, name))
+		imports = append(imports, terraformImport(terraformHostType, name, host.Data.ID))
+	}
+
+	for i := range rules.Data {
+		rule := rules.Rule(i)
+
+		name := terraformName(names, "rule_"+rule.Data.ID)
+		blocks = append(blocks, terraformRule(&rule, name))
+		imports = append(imports, terraformImport(terraformRuleType, name, rule.Data.ID))
+	}
+
+	for i, b := range append(blocks, imports...) {
+		if i > 0 {
+			if _, err = io.WriteString(w, "\n"); err != nil {
+				return fmt.Errorf("exportTerraform: %w", err)
+			}
+		}
+
+		if err = b.write(w, 0); err != nil {
+			return fmt.Errorf("exportTerraform: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// terraformProvider pins the provider so the resources are not looked up as
+// hashicorp/easyredir.
+func terraformProvider() hclBlock {
+	return hclBlock{
+		Header: "terraform",
+		Blocks: []hclBlock{{
+			Header: "required_providers",
+			Attributes: [][2]string{
+				{"easyredir", fmt.Sprintf("{ source = %s }", hclString(terraformProviderSource))},
+			},
+		}},
+	}
+}
+
+func terraformRule(rule *easyredir.Rule, name string) hclBlock {
+	a := rule.Data.Attributes
+
+	sources := []string{}
+	for _, s := range a.SourceUrls {
+		sources = append(sources, hclString(exportSource(s)))
+	}
+
+	return hclBlock{
+		Header: fmt.Sprintf("resource %q %q", terraformRuleType, name),
+		Attributes: [][2]string{
+			{"source_urls", "[" + strings.Join(sources, ", ") + "]"},
+			{"target_url", hclString(a.TargetURL)},
+			{"response_type", hclString(a.ResponseType)},
+			{"forward_params", strconv.FormatBool(a.ForwardParams)},
+			{"forward_path", strconv.FormatBool(a.ForwardPath)},
+		},
+	}
+}
+
+// terraformHost writes the host settings that can be changed. Settings the
+// API leaves unset are left out so the provider keeps its defaults.
+func terraformHost(host *easyredir.Host, name string) hclBlock {
+	o := sourceOptions(host)
+	a := host.Data.Attributes
+
+	matchOptions := hclBlock{Header: "match_options"}
+	matchOptions.bool("case_insensitive", o.MatchOptions.CaseInsensitive)
+	matchOptions.bool("slash_insensitive", o.MatchOptions.SlashInsensitive)
+
+	notFoundAction := hclBlock{Header: "not_found_action"}
+	notFoundAction.bool("forward_params", o.NotFoundAction.ForwardParams)
+	notFoundAction.bool("forward_path", o.NotFoundAction.ForwardPath)
+	if o.NotFoundAction.ResponseCode != nil {
+		notFoundAction.attribute("response_code", strconv.Itoa(*o.NotFoundAction.ResponseCode))
+	}
+	if o.NotFoundAction.ResponseURL != nil {
+		notFoundAction.attribute("response_url", hclString(*o.NotFoundAction.ResponseURL))
+	}
+	if o.NotFoundAction.Custom404Body != nil {
+		notFoundAction.attribute("custom_404_body", hclString(*o.NotFoundAction.Custom404Body))
+	}
+
+	security := hclBlock{Header: "security"}
+	security.bool("https_upgrade", o.Security.HTTPSUpgrade)
+	security.bool("prevent_foreign_embedding", o.Security.PreventForeignEmbedding)
+	security.bool("hsts_include_sub_domains", o.Security.HSTSIncludeSubDomains)
+	security.bool("hsts_preload", o.Security.HSTSPreload)
+	if a.Security.HstsMaxAge != nil {
+		security.attribute("hsts_max_age", strconv.Itoa(*o.Security.HSTSMaxAge))
+	}
+
+	b := hclBlock{
+		Header:     fmt.Sprintf("resource %q %q", terraformHostType, name),
+		Attributes: [][2]string{{"name", hclString(a.Name)}},
+	}
+
+	for _, nb := range []hclBlock{matchOptions, notFoundAction, security} {
+		if len(nb.Attributes) > 0 {
+			b.Blocks = append(b.Blocks, nb)
+		}
+	}
+
+	return b
+}
+
+func terraformImport(resourceType string, name string, id string) hclBlock {
+	return hclBlock{
+		Header: "import",
+		Attributes: [][2]string{
+			{"to", resourceType + "." + name},
+			{"id", hclString(id)},
+		},
+	}
+}
+
+// terraformName makes a unique resource name from a prefixed host name or ID.
+// Repeats are numbered.
+func terraformName(names map[string]int, s string) string {
+	name := terraformInvalid.ReplaceAllString(strings.ToLower(s), "_")
+
+	names[name]++
+	if n := names[name]; n > 1 {
+		name = fmt.Sprintf("%s_%d", name, n)
+	}
+
+	return name
+}
+
+// hclString quotes s as an HCL string literal. HCL only knows the escapes
+// \\, \", \n, \r, \t and \uNNNN, and interpolates ${ and %{ unless they
+// are doubled, so strconv.Quote cannot be used.
+func hclString(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			b.WriteRune(r)
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func (b *hclBlock) attribute(key string, value string) {
+	b.Attributes = append(b.Attributes, [2]string{key, value})
+}
+
+func (b *hclBlock) bool(key string, v *bool) {
+	if v != nil {
+		b.attribute(key, strconv.FormatBool(*v))
+	}
+}
+
+func (b *hclBlock) write(w io.Writer, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	if _, err := fmt.Fprintf(w, "%s%s {\n", indent, b.Header); err != nil {
+		return err
+	}
+
+	width := 0
+	for _, a := range b.Attributes {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+
+	for _, a := range b.Attributes {
+		if _, err := fmt.Fprintf(w, "%s  %-*s = %s\n", indent, width, a[0], a[1]); err != nil {
+			return err
+		}
+	}
+
+	for i, nb := range b.Blocks {
+		if i > 0 || len(b.Attributes) > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := nb.write(w, depth+1); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s}\n", indent)
+
+	return err
+}
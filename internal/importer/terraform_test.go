@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func TestHCLString(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "plain", s: "https://new.com/a", want: `"https://new.com/a"`},
+		{name: "quote and backslash", s: `say "hi" \o/`, want: `"say \"hi\" \\o/"`},
+		{name: "whitespace escapes", s: "a\nb\rc\td", want: `"a\nb\rc\td"`},
+		{name: "control characters", s: "a\x00b\x1bc\x7f", want: `"a\u0000b\u001Bc\u007F"`},
+		{name: "interpolation", s: "${var}/%{if x}", want: `"$${var}/%%{if x}"`},
+		{name: "lone markers", s: "$5 100%", want: `"$5 100%"`},
+		{name: "unicode", s: "https://new.com/café", want: `"https://new.com/café"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hclString(tt.s); got != tt.want {
+				t.Errorf("hclString(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportTerraform(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+	rule := s.MustAddRule(t, easyredirtest.NewRule("https://new.com", "old.com"))
+	host := s.Hosts()[0]
+
+	want := strings.NewReplacer("RULE", rule.Data.ID, "HOST", host.Data.ID).Replace(`terraform {
+  required_providers {
+    easyredir = { source = "mikelorant/easyredir" }
+  }
+}
+
+resource "easyredir_host" "host_old_com" {
+  name = "old.com"
+
+  match_options {
+    case_insensitive  = false
+    slash_insensitive = false
+  }
+
+  not_found_action {
+    forward_params = false
+    forward_path   = false
+    response_code  = 404
+  }
+
+  security {
+    https_upgrade             = false
+    prevent_foreign_embedding = false
+    hsts_include_sub_domains  = false
+    hsts_preload              = false
+    hsts_max_age              = 0
+  }
+}
+
+resource "easyredir_rule" "rule_RULE" {
+  source_urls    = ["old.com"]
+  target_url     = "https://new.com"
+  response_type  = "moved_permanently"
+  forward_params = false
+  forward_path   = false
+}
+
+import {
+  to = easyredir_host.host_old_com
+  id = "HOST"
+}
+
+import {
+  to = easyredir_rule.rule_RULE
+  id = "RULE"
+}
+`)
+
+	var w strings.Builder
+	if err := exportTerraform(testContext(t), c, &w); err != nil {
+		t.Fatalf("exportTerraform: %v", err)
+	}
+
+	if got := w.String(); got != want {
+		t.Errorf("exportTerraform() =\n%s\nwant\n%s", got, want)
+	}
+}
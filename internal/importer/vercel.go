@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tailscale/hujson"
+
+	_ "embed"
+)
+
+//go:embed vercel_print.tmpl
+var vercelPrintTemplate string
+
+func init() {
+	Register(Format{
+		Name: "vercel",
+		New:  func() Importer { return &VercelRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return filepath.Base(file) == "vercel.json" || (hasExtension(file, ".json") && bytes.Contains(content, []byte(`"redirects"`)))
+		},
+	})
+
+	RegisterExport(ExportFormat{Name: "vercel", Export: ruleExporter(exportVercel)})
+}
+
+type VercelRedirects []VercelRedirect
+
+type VercelRedirect struct {
+	Source      string
+	Destination string
+	Status      int
+	simpleRedirect
+}
+
+type vercelConfig struct {
+	Redirects []vercelRedirect `json:"redirects"`
+}
+
+type vercelRedirect struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Permanent   *bool             `json:"permanent,omitempty"`
+	StatusCode  int               `json:"statusCode,omitempty"`
+	Has         []vercelCondition `json:"has,omitempty"`
+	Missing     []vercelCondition `json:"missing,omitempty"`
+}
+
+type vercelCondition struct {
+	Type  string `json:"type"`
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+var (
+	// vercelWildcard matches a trailing parameter that takes the rest of the
+	// path, such as /:path*, /:slug(.*) or /(.*).
+	vercelWildcard = regexp.MustCompile(`/(?::[a-zA-Z_][a-zA-Z0-9_]*(?:\*|\(\.\*\))|\(\.\*\))$`)
+)
+
+// Load reads the redirects of a vercel.json file. A host condition sets the
+// source host; otherwise the given host is used.
+func (rs *VercelRedirects) Load(file string, host string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	config := vercelConfig{}
+	if err = hujson.Unmarshal(content, &config); err != nil {
+		log.Error().Err(fmt.Errorf("%s: %w", file, err)).Msg("")
+		return
+	}
+
+	for i, v := range config.Redirects {
+		r, err := vercelImport(v, host)
+		if err != nil {
+			log.Warn().Msgf("%s: redirect %d: %s", file, i+1, err)
+			continue
+		}
+
+		*rs = append(*rs, *r)
+	}
+
+	return
+}
+
+func vercelImport(v vercelRedirect, host string) (*VercelRedirect, error) {
+	status := v.StatusCode
+	if status == 0 {
+		status = 307
+		if v.Permanent != nil && *v.Permanent {
+			status = 308
+		}
+	}
+
+	r := &VercelRedirect{
+		Source:      v.Source,
+		Destination: v.Destination,
+		Status:      status,
+		// Vercel keeps the query string on redirects.
+		simpleRedirect: simpleRedirect{ForwardParams: true},
+	}
+
+	responseType, ok := responseTypeForStatus(status)
+	if !ok {
+		return nil, fmt.Errorf("status %d from %s is not a redirect", status, v.Source)
+	}
+	r.ResponseType = responseType
+
+	if len(v.Missing) > 0 {
+		return nil, fmt.Errorf("missing conditions on %s cannot be converted", v.Source)
+	}
+
+	for _, c := range v.Has {
+		if c.Type != "host" || strings.ContainsAny(c.Value, "()*:") {
+			return nil, fmt.Errorf("%s condition on %s cannot be converted", c.Type, v.Source)
+		}
+		host = c.Value
+	}
+
+	source := v.Source
+	target := v.Destination
+
+	if m := vercelWildcard.FindString(source); m != "" {
+		source = strings.TrimSuffix(source, m) + "/"
+
+		param := "$1"
+		if name := strings.TrimPrefix(m, "/:"); name != m {
+			param = ":" + strings.TrimSuffix(strings.TrimSuffix(name, "*"), "(.*)")
+		}
+
+		switch {
+		case strings.HasSuffix(target, param+"*"):
+			target = strings.TrimSuffix(target, param+"*")
+		case strings.HasSuffix(target, param):
+			target = strings.TrimSuffix(target, param)
+		default:
+			return nil, fmt.Errorf("%s matches a path prefix but %s does not use it", v.Source, v.Destination)
+		}
+
+		r.ForwardPath = true
+	}
+
+	if strings.ContainsAny(source, ":()*") || strings.Contains(target, "/:") || strings.Contains(target, "$") {
+		return nil, fmt.Errorf("parameters in %s %s cannot be converted", v.Source, v.Destination)
+	}
+
+	source, err := splatSource(source, host, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if r.TargetURL, err = absoluteTarget(target, host); err != nil {
+		return nil, err
+	}
+
+	r.SourceURLs = []string{source}
+
+	return r, nil
+}
+
+// exportVercel writes rules as the redirects of a vercel.json file. Vercel
+// sources are paths, so each source host becomes a host condition.
+func exportVercel(rules []easyredir.Rule, w io.Writer) error {
+	config := vercelConfig{Redirects: []vercelRedirect{}}
+
+	for _, rule := range rules {
+		a := rule.Data.Attributes
+
+		if !a.ForwardParams {
+			log.Warn().Msgf("rule %s drops the query string, which Vercel always keeps", rule.Data.ID)
+		}
+
+		for _, s := range a.SourceUrls {
+			u, err := sourceURL(s)
+			if err != nil {
+				return fmt.Errorf("exportVercel: %w", err)
+			}
+
+			source := u.EscapedPath()
+			if source == "" {
+				source = "/"
+			}
+
+			destination := a.TargetURL
+			if a.ForwardPath {
+				source = strings.TrimRight(source, "/") + "/:path*"
+				destination = strings.TrimRight(destination, "/") + "/:path*"
+			}
+
+			config.Redirects = append(config.Redirects, vercelRedirect{
+				Source:      source,
+				Destination: destination,
+				StatusCode:  statusForResponseType(a.ResponseType),
+				Has:         []vercelCondition{{Type: "host", Value: u.Hostname()}},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(config); err != nil {
+		return fmt.Errorf("exportVercel: unable to encode json: %w", err)
+	}
+
+	return nil
+}
+
+func (rs *VercelRedirects) Parse(file string, options *Options) Redirects {
+	rs.Load(file, options.Host)
+
+	return rs.redirects()
+}
+
+func (rs *VercelRedirects) redirects() Redirects {
+	return configRedirects(*rs)
+}
+
+func (r *VercelRedirect) Print() {
+	printConfig(vercelPrintTemplate, r)
+}
@@ -1,18 +1,12 @@
 package importer
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
-	"os"
+	"regexp"
 	"strings"
-	"text/template"
 	"time"
 
-	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
-
-	"github.com/alecthomas/chroma/quick"
-	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
 
@@ -22,47 +16,60 @@ import (
 //go:embed yaml_print.tmpl
 var yamlPrintTemplate string
 
+// yamlContent matches the top level list of a redirect spec.
+var yamlContent = regexp.MustCompile(`(?m)^-(\s|$)`)
+
+func init() {
+	Register(Format{
+		Name: "yaml",
+		New:  func() Importer { return &YAMLRedirects{} },
+		Detect: func(file string, content []byte) bool {
+			return hasExtension(file, ".yaml", ".yml") && yamlContent.Match(content)
+		},
+	})
+}
+
 type YAMLRedirects []YAMLRedirect
 
 type YAMLRedirect struct {
-	Meta          YAMLRedirectMeta     `yaml:"meta"`
-	Sources       []YAMLRedirectSource `yaml:"sources"`
-	TargetURL     *string              `yaml:"target_url"`
-	ForwardParams *bool                `yaml:"forward_params"`
-	ForwardPath   *bool                `yaml:"forward_path"`
-	ResponseType  *string              `yaml:"response_type"`
+	Meta          YAMLRedirectMeta     `yaml:"meta,omitempty"`
+	Sources       []YAMLRedirectSource `yaml:"sources,omitempty"`
+	TargetURL     *string              `yaml:"target_url,omitempty"`
+	ForwardParams *bool                `yaml:"forward_params,omitempty"`
+	ForwardPath   *bool                `yaml:"forward_path,omitempty"`
+	ResponseType  *string              `yaml:"response_type,omitempty"`
 }
 
 type YAMLRedirectMeta struct {
-	Name        *string    `yaml:"name"`
-	Description *string    `yaml:"description"`
-	Expires     *time.Time `yaml:"expires"`
+	Name        *string    `yaml:"name,omitempty"`
+	Description *string    `yaml:"description,omitempty"`
+	Expires     *time.Time `yaml:"expires,omitempty"`
 }
 
 type YAMLRedirectSource struct {
-	URL     *string                   `yaml:"url"`
-	Options YAMLRedirectSourceOptions `yaml:"options"`
+	URL     *string                   `yaml:"url,omitempty"`
+	Options YAMLRedirectSourceOptions `yaml:"options,omitempty"`
 }
 
 type YAMLRedirectSourceOptions struct {
 	MatchOptions struct {
-		CaseInsensitive  *bool `yaml:"case_insensitive"`
-		SlashInsensitive *bool `yaml:"slash_insensitive"`
-	} `yaml:"match_options"`
+		CaseInsensitive  *bool `yaml:"case_insensitive,omitempty"`
+		SlashInsensitive *bool `yaml:"slash_insensitive,omitempty"`
+	} `yaml:"match_options,omitempty"`
 	NotFoundAction struct {
-		ForwardParams *bool   `yaml:"forward_params"`
-		ForwardPath   *bool   `yaml:"forward_path"`
-		Custom404Body *string `yaml:"custom_404_body"`
-		ResponseCode  *int    `yaml:"response_code"`
-		ResponseURL   *string `yaml:"response_url"`
-	} `yaml:"not_found_action"`
+		ForwardParams *bool   `yaml:"forward_params,omitempty"`
+		ForwardPath   *bool   `yaml:"forward_path,omitempty"`
+		Custom404Body *string `yaml:"custom_404_body,omitempty"`
+		ResponseCode  *int    `yaml:"response_code,omitempty"`
+		ResponseURL   *string `yaml:"response_url,omitempty"`
+	} `yaml:"not_found_action,omitempty"`
 	Security struct {
-		HTTPSUpgrade            *bool `yaml:"https_upgrade"`
-		PreventForeignEmbedding *bool `yaml:"prevent_foreign_embedding"`
-		HSTSIncludeSubDomains   *bool `yaml:"hsts_include_subdomains"`
-		HSTSMaxAge              *int  `yaml:"hsts_max_age"`
-		HSTSPreload             *bool `yaml:"hsts_preload"`
-	} `yaml:"security"`
+		HTTPSUpgrade            *bool `yaml:"https_upgrade,omitempty"`
+		PreventForeignEmbedding *bool `yaml:"prevent_foreign_embedding,omitempty"`
+		HSTSIncludeSubDomains   *bool `yaml:"hsts_include_subdomains,omitempty"`
+		HSTSMaxAge              *int  `yaml:"hsts_max_age,omitempty"`
+		HSTSPreload             *bool `yaml:"hsts_preload,omitempty"`
+	} `yaml:"security,omitempty"`
 }
 
 var (
@@ -83,25 +90,81 @@ var (
 	defaultSecurityHSTSPreload           bool = false
 )
 
-func (rs *YAMLRedirects) Load(file string) {
+func (rs *YAMLRedirects) Load(file string) error {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("Load: %w", err)
 	}
 
-	err = yaml.UnmarshalStrict(data, &rs)
-	if err != nil {
-		fmt.Println(err)
+	if err = yaml.UnmarshalStrict(data, rs); err != nil {
+		return fmt.Errorf("Load: %s: %w", file, err)
 	}
 
-	return
+	return nil
+}
+
+// Validate reports every redirect that is missing a target URL or a source
+// URL, so a rule is never built from an incomplete entry.
+func (rs *YAMLRedirects) Validate() error {
+	problems := []string{}
+
+	for i := range *rs {
+		if err := (*rs)[i].validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("redirect %d: %s", i+1, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Validate: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
 }
 
+func (r *YAMLRedirect) validate() error {
+	problems := []string{}
+
+	if r.TargetURL == nil || *r.TargetURL == "" {
+		problems = append(problems, "missing target_url")
+	}
+
+	if len(r.Sources) == 0 {
+		problems = append(problems, "missing sources")
+	}
+
+	for j, s := range r.Sources {
+		if s.URL == nil || *s.URL == "" {
+			problems = append(problems, fmt.Sprintf("source %d: missing url", j+1))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// Defaults fills in the rule settings and the host options the spec leaves
+// out, as a host created by an import gets them.
 func (rs *YAMLRedirects) Defaults() {
-	drs := &YAMLRedirects{}
+	rs.ruleDefaults()
 
-	for _, r := range *rs {
-		dr := &YAMLRedirect{}
+	for i := range *rs {
+		for j := range (*rs)[i].Sources {
+			s := &(*rs)[i].Sources[j]
+			s.Options = s.Options.withDefaults()
+		}
+	}
+
+	return
+}
+
+// ruleDefaults fills in only the rule settings, leaving undeclared host
+// options nil so that existing hosts keep their settings.
+func (rs *YAMLRedirects) ruleDefaults() {
+	for i := range *rs {
+		r := &(*rs)[i]
 
 		if r.ForwardParams == nil {
 			r.ForwardParams = &defaultForwardParams
@@ -112,161 +175,88 @@ func (rs *YAMLRedirects) Defaults() {
 		if r.ResponseType == nil {
 			r.ResponseType = &defaultResponseType
 		}
-
-		for _, s := range r.Sources {
-			if s.Options.MatchOptions.CaseInsensitive == nil {
-				s.Options.MatchOptions.CaseInsensitive = &defaultMatchOptionsCaseInsensitive
-			}
-			if s.Options.MatchOptions.SlashInsensitive == nil {
-				s.Options.MatchOptions.SlashInsensitive = &defaultMatchOptionsSlashInsensitive
-			}
-
-			if s.Options.NotFoundAction.ForwardParams == nil {
-				s.Options.NotFoundAction.ForwardParams = &defaultNotFoundActionForwardParams
-			}
-			if s.Options.NotFoundAction.ForwardPath == nil {
-				s.Options.NotFoundAction.ForwardPath = &defaultNotFoundActionForwardPath
-			}
-			if s.Options.NotFoundAction.ResponseCode == nil {
-				s.Options.NotFoundAction.ResponseCode = &defaultNotFoundActionResponseCode
-			}
-
-			if s.Options.Security.HTTPSUpgrade == nil {
-				s.Options.Security.HTTPSUpgrade = &defaultSecurityHTTPSUpgrade
-			}
-			if s.Options.Security.HSTSIncludeSubDomains == nil {
-				s.Options.Security.HSTSIncludeSubDomains = &defaultSecurityHSTSIncludeSubDomains
-			}
-			if s.Options.Security.HSTSMaxAge == nil {
-				s.Options.Security.HSTSMaxAge = &defaultSecurityHSTSMaxAge
-			}
-			if s.Options.Security.HSTSPreload == nil {
-				s.Options.Security.HSTSPreload = &defaultSecurityHSTSPreload
-			}
-
-			dr.Sources = append(dr.Sources, s)
-		}
-
-		dr.Meta = r.Meta
-		dr.TargetURL = r.TargetURL
-		dr.ForwardParams = r.ForwardParams
-		dr.ForwardPath = r.ForwardPath
-		dr.ResponseType = r.ResponseType
-
-		*drs = append(*drs, *dr)
 	}
-	*rs = *drs
 
 	return
 }
 
-func (r *YAMLRedirect) Print() {
-	fmt.Printf("%s:\n", text.FgCyan.Sprint("CONFIG"))
-	fmt.Println()
-
-	var w bytes.Buffer
+// withDefaults returns the options with the settings of a new host filled in
+// for those left out.
+func (o YAMLRedirectSourceOptions) withDefaults() YAMLRedirectSourceOptions {
+	if o.MatchOptions.CaseInsensitive == nil {
+		o.MatchOptions.CaseInsensitive = &defaultMatchOptionsCaseInsensitive
+	}
+	if o.MatchOptions.SlashInsensitive == nil {
+		o.MatchOptions.SlashInsensitive = &defaultMatchOptionsSlashInsensitive
+	}
 
-	t := template.Must(template.New("").Parse(yamlPrintTemplate))
-	t.Execute(&w, r)
+	if o.NotFoundAction.ForwardParams == nil {
+		o.NotFoundAction.ForwardParams = &defaultNotFoundActionForwardParams
+	}
+	if o.NotFoundAction.ForwardPath == nil {
+		o.NotFoundAction.ForwardPath = &defaultNotFoundActionForwardPath
+	}
+	if o.NotFoundAction.ResponseCode == nil {
+		o.NotFoundAction.ResponseCode = &defaultNotFoundActionResponseCode
+	}
 
-	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
+	if o.Security.HTTPSUpgrade == nil {
+		o.Security.HTTPSUpgrade = &defaultSecurityHTTPSUpgrade
+	}
+	if o.Security.HSTSIncludeSubDomains == nil {
+		o.Security.HSTSIncludeSubDomains = &defaultSecurityHSTSIncludeSubDomains
+	}
+	if o.Security.HSTSMaxAge == nil {
+		o.Security.HSTSMaxAge = &defaultSecurityHSTSMaxAge
+	}
+	if o.Security.HSTSPreload == nil {
+		o.Security.HSTSPreload = &defaultSecurityHSTSPreload
+	}
 
-	fmt.Println()
+	return o
+}
 
-	return
+func (r *YAMLRedirect) Print() {
+	printConfig(yamlPrintTemplate, r)
 }
 
-func (rs *YAMLRedirects) Import(preview bool) {
-	c, err := easyredir.NewClient()
-	if err != nil {
+func (rs *YAMLRedirects) Parse(file string, options *Options) Redirects {
+	if err := rs.Load(file); err != nil {
 		log.Error().Err(err).Msg("")
-		return
+		return Redirects{}
 	}
+	rs.Defaults()
 
-	for _, r := range *rs {
-		r.Print()
-
-		if preview == true {
-			continue
-		}
+	return rs.redirects()
+}
 
-		rule := easyredir.Rule{}
-		rule.Data.Attributes.ForwardParams = *r.ForwardParams
-		rule.Data.Attributes.ForwardPath = *r.ForwardPath
-		rule.Data.Attributes.ResponseType = *r.ResponseType
+// redirects carries the options of each host from the first source naming
+// it, matching how Apply configures them. Incomplete redirects are reported
+// and left out before a rule is built from them.
+func (rs *YAMLRedirects) redirects() (out Redirects) {
+	out.Hosts = make(map[string]YAMLRedirectSourceOptions)
 
-		for _, v := range r.Sources {
-			rule.Data.Attributes.SourceUrls = append(rule.Data.Attributes.SourceUrls, *v.URL)
+	for i := range *rs {
+		r := &(*rs)[i]
+		if err := r.validate(); err != nil {
+			log.Warn().Msgf("skipping redirect %d: %s", i+1, err)
+			continue
 		}
-		rule.Data.Attributes.TargetURL = *r.TargetURL
 
-		res, err := c.CreateRule(&rule)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			return
-		}
+		out.Redirects = append(out.Redirects, Redirect{Rule: r.rule(), Config: r})
 
-		res.Print()
-
-		for i, v := range res.Data.Relationships.SourceHosts.Data {
-			host := &easyredir.Host{}
-			host.Data.ID = v.ID
-
-			source := strings.TrimRight(res.Data.Attributes.SourceUrls[i], "/")
-
-			for _, s := range r.Sources {
-				if *s.URL == source {
-					if s.Options.MatchOptions.CaseInsensitive != nil {
-						host.Data.Attributes.MatchOptions.CaseInsensitive = *s.Options.MatchOptions.CaseInsensitive
-					}
-					if s.Options.MatchOptions.SlashInsensitive != nil {
-						host.Data.Attributes.MatchOptions.SlashInsensitive = *s.Options.MatchOptions.SlashInsensitive
-					}
-
-					if s.Options.NotFoundAction.ForwardParams != nil {
-						host.Data.Attributes.NotFoundAction.ForwardParams = *s.Options.NotFoundAction.ForwardParams
-					}
-					if s.Options.NotFoundAction.ForwardPath != nil {
-						host.Data.Attributes.NotFoundAction.ForwardPath = *s.Options.NotFoundAction.ForwardPath
-					}
-					if s.Options.NotFoundAction.Custom404Body != nil {
-						host.Data.Attributes.NotFoundAction.Custom404Body = *s.Options.NotFoundAction.Custom404Body
-					}
-					if s.Options.NotFoundAction.ResponseCode != nil {
-						host.Data.Attributes.NotFoundAction.ResponseCode = *s.Options.NotFoundAction.ResponseCode
-					}
-					if s.Options.NotFoundAction.ResponseURL != nil {
-						host.Data.Attributes.NotFoundAction.ResponseURL = *s.Options.NotFoundAction.ResponseURL
-					}
-
-					if s.Options.Security.HTTPSUpgrade != nil {
-						host.Data.Attributes.Security.HTTPSUpgrade = *s.Options.Security.HTTPSUpgrade
-					}
-					if s.Options.Security.PreventForeignEmbedding != nil {
-						host.Data.Attributes.Security.PreventForeignEmbedding = *s.Options.Security.PreventForeignEmbedding
-					}
-					if s.Options.Security.HSTSIncludeSubDomains != nil {
-						host.Data.Attributes.Security.HstsIncludeSubDomains = *s.Options.Security.HSTSIncludeSubDomains
-					}
-					if s.Options.Security.HSTSMaxAge != nil {
-						host.Data.Attributes.Security.HstsMaxAge = *s.Options.Security.HSTSMaxAge
-					}
-					if s.Options.Security.HSTSPreload != nil {
-						host.Data.Attributes.Security.HstsPreload = *s.Options.Security.HSTSPreload
-					}
-					break
-				}
+		for _, src := range r.Sources {
+			if src.URL == nil {
+				continue
 			}
 
-			res, err := c.UpdateHost(host)
-			if err != nil {
-				log.Error().Err(err).Msg("")
-				return
+			name := strings.ToLower(sourceHost(*src.URL))
+			if _, ok := out.Hosts[name]; name == "" || ok {
+				continue
 			}
-
-			res.Print()
+			out.Hosts[name] = src.Options
 		}
 	}
 
-	return
+	return out
 }
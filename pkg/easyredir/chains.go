@@ -0,0 +1,156 @@
+package easyredir
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+const (
+	ChainMultiHop string = "chain"
+	ChainLoop     string = "loop"
+	ChainNotFound string = "not_found"
+
+	// maxChainHops stops following a chain that keeps growing without
+	// revisiting a URL.
+	maxChainHops = 20
+)
+
+// Chain is a source URL whose redirect does not end in a single hop. Hops
+// holds every response along the way, starting with the rule itself.
+type Chain struct {
+	Kind      string
+	RuleID    string
+	SourceURL string
+	Hops      []Resolution
+}
+
+type Chains []Chain
+
+// Chains follows the target of every source URL back through the rules and
+// reports multi-hop chains, loops and targets that land on a host's not
+// found action. Targets on hosts the simulator knows nothing about are
+// treated as final.
+func (s *Simulator) Chains() (chains Chains) {
+	for _, r := range s.Rules {
+		for _, src := range r.Data.Attributes.SourceUrls {
+			if c, ok := s.chain(r.Data.ID, src); ok {
+				chains = append(chains, c)
+			}
+		}
+	}
+
+	return chains
+}
+
+func (s *Simulator) chain(ruleID string, source string) (c Chain, ok bool) {
+	c.RuleID = ruleID
+	c.SourceURL = source
+
+	// Sources without a scheme match both, so start on https to avoid
+	// counting the host's HTTPS upgrade as a hop.
+	next := source
+	if !strings.Contains(next, "://") {
+		next = "https://" + next
+	}
+
+	visited := map[string]bool{}
+
+	for len(c.Hops) < maxChainHops {
+		u, err := parseSource(next)
+		if err != nil {
+			break
+		}
+
+		key := chainKey(u.Scheme, u.Hostname(), u.EscapedPath(), u.RawQuery)
+		if visited[key] {
+			c.Kind = ChainLoop
+			return c, true
+		}
+		visited[key] = true
+
+		res, err := s.Resolve(next)
+		if err != nil {
+			break
+		}
+
+		if len(c.Hops) > 0 && (res.Reason == ReasonNotFound || res.Reason == ReasonUnknownHost) {
+			c.Hops = append(c.Hops, res)
+			c.Kind = ChainNotFound
+			return c, true
+		}
+
+		c.Hops = append(c.Hops, res)
+
+		if res.Location == "" {
+			break
+		}
+
+		target, err := parseSource(res.Location)
+		if err != nil || !s.serves(target.Hostname()) {
+			break
+		}
+
+		next = res.Location
+	}
+
+	if len(c.Hops) > 1 {
+		c.Kind = ChainMultiHop
+		return c, true
+	}
+
+	return c, false
+}
+
+// serves reports whether requests to the host would reach EasyRedir.
+func (s *Simulator) serves(hostname string) bool {
+	if s.host(hostname) != nil {
+		return true
+	}
+
+	for _, r := range s.Rules {
+		for _, src := range r.Data.Attributes.SourceUrls {
+			if u, err := parseSource(src); err == nil && strings.EqualFold(u.Hostname(), hostname) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func chainKey(scheme string, host string, path string, query string) string {
+	key := fmt.Sprintf("%s://%s%s", scheme, strings.ToLower(host), strings.TrimRight(path, "/"))
+	if query != "" {
+		key += "?" + query
+	}
+
+	return key
+}
+
+func (cs Chains) Print() {
+	t := newTable(os.Stdout)
+
+	t.AppendHeader(table.Row{"KIND", "RULE", "SOURCE URL", "HOPS", "PATH"})
+	for _, c := range cs {
+		row := []table.Row{}
+		for i, h := range c.Hops {
+			step := fmt.Sprintf("%d %s", h.StatusCode, h.Location)
+			if h.Location == "" {
+				step = fmt.Sprintf("%d (%s)", h.StatusCode, h.Reason)
+			}
+
+			if i == 0 {
+				row = append(row, table.Row{c.Kind, c.RuleID, c.SourceURL, len(c.Hops), step})
+				continue
+			}
+			row = append(row, table.Row{"", "", "", "", step})
+		}
+		t.AppendRows(row)
+	}
+	t.Render()
+
+	return
+}
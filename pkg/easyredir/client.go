@@ -1,45 +1,36 @@
 package easyredir
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
-	"text/template"
+	"time"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/google/uuid"
-	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/spf13/viper"
-
-	_ "embed"
 )
 
 const (
 	baseURLV1 = "https://api.easyredir.com/v1"
-)
 
-//go:embed client_error.tmpl
-var clientErrorTemplate string
+	defaultUserAgent = "easyredir-cli"
+)
 
 type Client struct {
 	baseURL    string
 	apiKey     string
 	apiSecret  string
+	userAgent  string
+	timeout    time.Duration
+	logger     zerolog.Logger
+	limiter    *rateLimiter
 	HTTPClient *http.Client
-}
 
-type errorResponse struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
-	Errors  []struct {
-		Resource string `json:"resource"`
-		Param    string `json:"param"`
-		Code     string `json:"code"`
-		Message  string `json:"message"`
-	} `json:"errors"`
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
 }
 
 type Meta struct {
@@ -51,20 +42,39 @@ type Links struct {
 	Prev string `json:"prev"`
 }
 
-func NewClient() (c *Client, err error) {
-	if key := viper.GetString("api.key"); key == "" {
-		return nil, fmt.Errorf("NewClient: missing api.key")
+func NewClient(opts ...Option) (c *Client, err error) {
+	c = &Client{
+		baseURL:    baseURLV1,
+		userAgent:  defaultUserAgent,
+		logger:     log.Logger,
+		limiter:    &rateLimiter{},
+		HTTPClient: &http.Client{},
+
+		MaxRetries:   defaultMaxRetries,
+		RetryWaitMin: defaultRetryWaitMin,
+		RetryWaitMax: defaultRetryWaitMax,
 	}
 
-	if secret := viper.GetString("api.secret"); secret == "" {
-		return nil, fmt.Errorf("NewClient: missing api.secret")
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	c = &Client{
-		baseURL:    baseURLV1,
-		apiKey:     viper.GetString("api.key"),
-		apiSecret:  viper.GetString("api.secret"),
-		HTTPClient: &http.Client{},
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("NewClient: missing api key")
+	}
+
+	if c.apiSecret == "" {
+		return nil, fmt.Errorf("NewClient: missing api secret")
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{}
+	}
+
+	if c.timeout != 0 {
+		hc := *c.HTTPClient
+		hc.Timeout = c.timeout
+		c.HTTPClient = &hc
 	}
 
 	return c, nil
@@ -74,12 +84,13 @@ func (c *Client) sendRequest(req *http.Request, v interface{}) (err error) {
 	req.SetBasicAuth(c.apiKey, c.apiSecret)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", c.userAgent)
 
 	if req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH" {
 		req.Header.Set("Idempotency-Key", uuid.NewString())
 	}
 
-	res, err := c.HTTPClient.Do(req)
+	res, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("sendRequest: unable to send request: %w", err)
 	}
@@ -87,13 +98,14 @@ func (c *Client) sendRequest(req *http.Request, v interface{}) (err error) {
 	defer res.Body.Close()
 
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		errRes := errorResponse{}
-		if err = json.NewDecoder(res.Body).Decode(&errRes); err == nil {
-			errRes.Print()
-			return fmt.Errorf("sendRequest: error message: %s type: %s", errRes.Message, errRes.Type)
-		}
+		// An undecodable body still yields an APIError with the status code.
+		apiErr := &APIError{}
+		json.NewDecoder(res.Body).Decode(apiErr)
+
+		apiErr.StatusCode = res.StatusCode
+		apiErr.RateLimit = rateLimitFromResponse(res)
 
-		return fmt.Errorf("sendRequest: unknown error, status code: %d", res.StatusCode)
+		return fmt.Errorf("sendRequest: %w", apiErr)
 	}
 
 	if res.ContentLength == 0 {
@@ -104,27 +116,48 @@ func (c *Client) sendRequest(req *http.Request, v interface{}) (err error) {
 		return fmt.Errorf("sendRequest: unable to decode JSON into struct: %w", err)
 	}
 
-	if res.Header["x-ratelimit-limit"] != nil {
-		log.Warn().Msg(fmt.Sprintf("Rate limit: %s", res.Header["x-ratelimit-limit"]))
-		log.Warn().Msg(fmt.Sprintf("Rate limit remaining: %s", res.Header["x-ratelimit-remaining"]))
-		log.Warn().Msg(fmt.Sprintf("Rate limit reset: %s", res.Header["x-ratelimit-reset"]))
-	}
-
 	return nil
 }
 
-func (e *errorResponse) Print() {
-	fmt.Printf("%s:\n", text.FgRed.Sprint("ERROR"))
-	fmt.Println()
+// do sends the request, retrying transient failures. The request headers,
+// including the Idempotency-Key, are left untouched between attempts so a
+// retried POST cannot create the same rule twice.
+func (c *Client) do(req *http.Request) (res *http.Response, err error) {
+	for attempt := 0; ; attempt++ {
+		if err = c.limiter.wait(req.Context(), c.logger); err != nil {
+			return nil, err
+		}
 
-	var w bytes.Buffer
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("do: unable to rewind request body: %w", err)
+			}
+		}
 
-	t := template.Must(template.New("").Parse(clientErrorTemplate))
-	t.Execute(&w, e)
+		res, err = c.HTTPClient.Do(req)
+		if res != nil {
+			c.limiter.update(res)
+		}
+
+		if attempt >= c.MaxRetries || !retryable(req, res, err) {
+			return res, err
+		}
 
-	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
+		wait := c.backoff(attempt, res)
 
-	fmt.Println()
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
 
-	return
+		c.logger.Warn().Msg(fmt.Sprintf("Request %s %s failed (%s), retrying in %s (%d/%d).", req.Method, req.URL.Path, reason, wait.Round(time.Millisecond), attempt+1, c.MaxRetries))
+
+		if err = sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
 }
@@ -0,0 +1,169 @@
+package easyredir_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []int
+		maxRetries   int
+		wantRequests int
+		wantStatus   int
+	}{
+		{
+			name:         "success",
+			maxRetries:   2,
+			wantRequests: 1,
+		},
+		{
+			name:         "server errors",
+			failures:     []int{http.StatusServiceUnavailable, http.StatusBadGateway},
+			maxRetries:   2,
+			wantRequests: 3,
+		},
+		{
+			name:         "rate limited",
+			failures:     []int{http.StatusTooManyRequests},
+			maxRetries:   2,
+			wantRequests: 2,
+		},
+		{
+			name:         "retries exhausted",
+			failures:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			maxRetries:   2,
+			wantRequests: 3,
+			wantStatus:   http.StatusInternalServerError,
+		},
+		{
+			name:         "client error",
+			failures:     []int{http.StatusBadRequest},
+			maxRetries:   2,
+			wantRequests: 1,
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			c.MaxRetries = tt.maxRetries
+			s.FailNext(tt.failures...)
+
+			_, err := c.ListRules(context.Background(), &easyredir.RulesOptions{})
+
+			if got := s.Requests(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("ListRules: %v", err)
+				}
+				return
+			}
+
+			var apiErr *easyredir.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an APIError", err)
+			}
+			if apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+	c.RetryWaitMin = time.Hour
+	c.RetryWaitMax = time.Hour
+	s.FailNext(http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.ListRules(ctx, &easyredir.RulesOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if got := s.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		wantStatus     int
+		wantType       string
+		wantFields     int
+		wantNotFound   bool
+		wantValidation bool
+		wantError      string
+	}{
+		{
+			name:         "not found",
+			wantStatus:   http.StatusNotFound,
+			wantType:     "invalid_request_error",
+			wantFields:   1,
+			wantNotFound: true,
+			wantError:    "api error: status code: 404 type: invalid_request_error message: No such rule: missing errors: rule.id: No such rule: missing",
+		},
+		{
+			name:       "unauthorized",
+			key:        "other",
+			wantStatus: http.StatusUnauthorized,
+			wantType:   "authentication_error",
+			wantError:  "api error: status code: 401 type: authentication_error message: Invalid API key or secret.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			s.Key, s.Secret = tt.key, "secret"
+
+			rule := easyredir.Rule{}
+			rule.Data.ID = "missing"
+
+			err := c.GetRule(context.Background(), &rule)
+
+			var apiErr *easyredir.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an APIError", err)
+			}
+
+			if apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+			}
+			if apiErr.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", apiErr.Type, tt.wantType)
+			}
+			if len(apiErr.Errors) != tt.wantFields {
+				t.Errorf("field errors = %d, want %d", len(apiErr.Errors), tt.wantFields)
+			}
+			if apiErr.RateLimit.Limit == 0 {
+				t.Errorf("rate limit not decoded from headers")
+			}
+			if got := easyredir.IsNotFound(err); got != tt.wantNotFound {
+				t.Errorf("IsNotFound = %t, want %t", got, tt.wantNotFound)
+			}
+			if got := easyredir.IsValidation(err); got != tt.wantValidation {
+				t.Errorf("IsValidation = %t, want %t", got, tt.wantValidation)
+			}
+			if got := apiErr.Error(); got != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
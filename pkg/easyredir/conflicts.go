@@ -0,0 +1,219 @@
+package easyredir
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Conflict is a source URL that another rule already claims. Existing is the
+// owning rule's source URL, which may differ from SourceURL only in ways the
+// host's match options treat as equal.
+type Conflict struct {
+	SourceURL string
+	Existing  string
+	RuleID    string
+}
+
+type Conflicts []Conflict
+
+// ConflictError is returned when a rule cannot be saved because its source
+// URLs are claimed by other rules.
+type ConflictError struct {
+	Conflicts Conflicts
+}
+
+func (e *ConflictError) Error() string {
+	s := []string{}
+	for _, c := range e.Conflicts {
+		owner := c.RuleID
+		if owner == "" {
+			owner = "a new rule"
+		}
+		s = append(s, fmt.Sprintf("%s is already used by %s", c.SourceURL, owner))
+	}
+
+	return fmt.Sprintf("conflicting source URLs: %s", strings.Join(s, ", "))
+}
+
+// Conflicts loads the rules on the hosts of the given rule and reports its
+// source URLs that are already claimed. The rule itself is ignored, so an
+// existing rule can be checked before it is updated.
+func (c *Client) Conflicts(ctx context.Context, rule *Rule) (Conflicts, error) {
+	s, err := c.Simulator(ctx, SourceHosts(rule.Data.Attributes.SourceUrls)...)
+	if err != nil {
+		return nil, fmt.Errorf("Conflicts: %w", err)
+	}
+
+	return s.Conflicts(rule), nil
+}
+
+// SourceHosts returns the distinct lower case host names of the source URLs,
+// skipping any that cannot be parsed.
+func SourceHosts(sources []string) (hostnames []string) {
+	seen := make(map[string]bool)
+
+	for _, src := range sources {
+		u, err := parseSource(src)
+		if err != nil {
+			continue
+		}
+
+		name := strings.ToLower(u.Hostname())
+		if !seen[name] {
+			seen[name] = true
+			hostnames = append(hostnames, name)
+		}
+	}
+
+	return hostnames
+}
+
+// Conflicts reports the source URLs of the rule that match a source URL of
+// another rule. Sources are compared the way the host's match options compare
+// requests, and a bare host is the same as its root path.
+func (s *Simulator) Conflicts(rule *Rule) (conflicts Conflicts) {
+	for _, src := range rule.Data.Attributes.SourceUrls {
+		for _, r := range s.Rules {
+			if rule.Data.ID != "" && r.Data.ID == rule.Data.ID {
+				continue
+			}
+
+			for _, existing := range r.Data.Attributes.SourceUrls {
+				if s.SameSource(src, existing) {
+					conflicts = append(conflicts, Conflict{
+						SourceURL: src,
+						Existing:  existing,
+						RuleID:    r.Data.ID,
+					})
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// SameSource reports whether two source URLs match the same requests.
+func (s *Simulator) SameSource(a string, b string) bool {
+	ua, err := parseSource(a)
+	if err != nil {
+		return false
+	}
+
+	ub, err := parseSource(b)
+	if err != nil {
+		return false
+	}
+
+	if !strings.EqualFold(ua.Hostname(), ub.Hostname()) {
+		return false
+	}
+
+	// Sources without a scheme match both.
+	if strings.Contains(a, "://") && strings.Contains(b, "://") && ua.Scheme != ub.Scheme {
+		return false
+	}
+
+	if ua.Query().Encode() != ub.Query().Encode() {
+		return false
+	}
+
+	pa, pb := ua.EscapedPath(), ub.EscapedPath()
+	if pa == "" {
+		pa = "/"
+	}
+	if pb == "" {
+		pb = "/"
+	}
+
+	if host := s.host(ua.Hostname()); host != nil {
+		if boolValue(host.Data.Attributes.MatchOptions.CaseInsensitive) {
+			pa, pb = strings.ToLower(pa), strings.ToLower(pb)
+		}
+		if boolValue(host.Data.Attributes.MatchOptions.SlashInsensitive) {
+			pa, pb = trimSlash(pa), trimSlash(pb)
+		}
+	}
+
+	return pa == pb
+}
+
+// Merge folds the rule into the rule that owns its conflicting source URLs.
+// The owner keeps its ID and source URLs and takes the target and options of
+// the rule.
+func (s *Simulator) Merge(owner Rule, rule *Rule) Rule {
+	merged := owner
+	merged.Data.Attributes = rule.Data.Attributes
+	merged.Data.Attributes.SourceUrls = append([]string{}, owner.Data.Attributes.SourceUrls...)
+
+	for _, src := range rule.Data.Attributes.SourceUrls {
+		claimed := false
+		for _, existing := range merged.Data.Attributes.SourceUrls {
+			if s.SameSource(src, existing) {
+				claimed = true
+				break
+			}
+		}
+
+		if !claimed {
+			merged.Data.Attributes.SourceUrls = append(merged.Data.Attributes.SourceUrls, src)
+		}
+	}
+
+	return merged
+}
+
+// Rule returns the rule with the given ID.
+func (s *Simulator) Rule(id string) (*Rule, bool) {
+	for i, r := range s.Rules {
+		if r.Data.ID == id {
+			return &s.Rules[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Owners returns the distinct IDs of the rules that own the conflicts.
+func (cs Conflicts) Owners() (ids []string) {
+	seen := make(map[string]bool)
+
+	for _, c := range cs {
+		if !seen[c.RuleID] {
+			seen[c.RuleID] = true
+			ids = append(ids, c.RuleID)
+		}
+	}
+
+	return ids
+}
+
+// Print writes the conflicts to stderr so they do not mix with the rule
+// written to stdout.
+func (cs Conflicts) Print() {
+	t := newTable(os.Stderr)
+
+	t.AppendHeader(table.Row{"SOURCE URL", "CLAIMED BY", "RULE"})
+	for _, c := range cs {
+		owner := c.RuleID
+		if owner == "" {
+			owner = "(new rule)"
+		}
+		t.AppendRow(table.Row{c.SourceURL, c.Existing, owner})
+	}
+	t.Render()
+
+	return
+}
+
+func trimSlash(p string) string {
+	if p == "/" {
+		return p
+	}
+
+	return strings.TrimRight(p, "/")
+}
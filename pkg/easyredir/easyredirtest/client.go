@@ -0,0 +1,58 @@
+package easyredirtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+
+	"github.com/rs/zerolog"
+)
+
+// NewClient starts a server that is closed when the test ends and returns it
+// with a client pointed at it. The client does not log and retries without
+// waiting long.
+func NewClient(t testing.TB) (*Server, *easyredir.Client) {
+	t.Helper()
+
+	s := NewServer()
+	t.Cleanup(s.Close)
+
+	c, err := easyredir.NewClient(
+		easyredir.WithBaseURL(s.BaseURL()),
+		easyredir.WithAPIKey("key", "secret"),
+		easyredir.WithLogger(zerolog.Nop()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	c.RetryWaitMin = time.Millisecond
+	c.RetryWaitMax = 5 * time.Millisecond
+
+	return s, c
+}
+
+// NewRule returns a moved_permanently rule from the sources to target that
+// forwards neither the path nor the query string.
+func NewRule(target string, sources ...string) easyredir.Rule {
+	r := easyredir.Rule{}
+	r.Data.Attributes.SourceUrls = sources
+	r.Data.Attributes.TargetURL = target
+	r.Data.Attributes.ResponseType = "moved_permanently"
+
+	return r
+}
+
+// MustAddRule is AddRule for tests, failing the test when the rule is
+// rejected.
+func (s *Server) MustAddRule(t testing.TB, r easyredir.Rule) easyredir.Rule {
+	t.Helper()
+
+	rule, err := s.AddRule(r)
+	if err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+
+	return rule
+}
@@ -0,0 +1,310 @@
+package easyredirtest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+var responseCodes = []int{301, 302, 404}
+
+type hostAttributes struct {
+	Name         *string `json:"name"`
+	MatchOptions *struct {
+		CaseInsensitive  *bool `json:"case_insensitive"`
+		SlashInsensitive *bool `json:"slash_insensitive"`
+	} `json:"match_options"`
+	Security *struct {
+		HTTPSUpgrade            *bool `json:"https_upgrade"`
+		PreventForeignEmbedding *bool `json:"prevent_foreign_embedding"`
+		HstsIncludeSubDomains   *bool `json:"hsts_include_sub_domains"`
+		HstsMaxAge              *int  `json:"hsts_max_age"`
+		HstsPreload             *bool `json:"hsts_preload"`
+	} `json:"security"`
+	NotFoundAction *struct {
+		ForwardParams *bool   `json:"forward_params"`
+		ForwardPath   *bool   `json:"forward_path"`
+		Custom404Body *string `json:"custom_404_body"`
+		ResponseCode  *int    `json:"response_code"`
+		ResponseURL   *string `json:"response_url"`
+	} `json:"not_found_action"`
+}
+
+// AddHost stores a host with default settings, or returns the existing host
+// with the same name.
+func (s *Server) AddHost(name string) easyredir.Host {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.hostFor(name)
+}
+
+// Hosts returns a copy of every stored host.
+func (s *Server) Hosts() (hosts []easyredir.Host) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range s.hosts {
+		hosts = append(hosts, *h)
+	}
+
+	return hosts
+}
+
+func (s *Server) handleHosts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listHosts(w, r)
+	case http.MethodPost:
+		attributes := hostAttributes{}
+		if err := decode(r, &attributes); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Unable to parse body: %s", err))
+			return
+		}
+
+		if attributes.Name == nil || *attributes.Name == "" {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", fieldError{
+				Resource: "host",
+				Param:    "name",
+				Code:     "missing",
+				Message:  "Name can't be blank.",
+			})
+			return
+		}
+
+		for _, h := range s.hosts {
+			if h.Data.Attributes.Name == *attributes.Name {
+				writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", fieldError{
+					Resource: "host",
+					Param:    "name",
+					Code:     "taken",
+					Message:  fmt.Sprintf("Host %s already exists.", *attributes.Name),
+				})
+				return
+			}
+		}
+
+		host := s.hostFor(*attributes.Name)
+		if errs := applyHost(host, attributes); len(errs) > 0 {
+			s.hosts = s.hosts[:len(s.hosts)-1]
+			writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", errs...)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, host)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) listHosts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ids := []string{}
+	for _, h := range s.hosts {
+		ids = append(ids, h.Data.ID)
+	}
+
+	start, end, hasMore, err := paginate(q, ids)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	p := page{Data: []interface{}{}}
+	for _, h := range s.hosts[start:end] {
+		item := struct {
+			ID         string `json:"id"`
+			Type       string `json:"type"`
+			Attributes struct {
+				Name              string `json:"name"`
+				DNSStatus         string `json:"dns_status"`
+				CertificateStatus string `json:"certificate_status"`
+			} `json:"attributes"`
+			Links struct {
+				Self string `json:"self"`
+			} `json:"links"`
+		}{}
+		item.ID = h.Data.ID
+		item.Type = h.Data.Type
+		item.Attributes.Name = h.Data.Attributes.Name
+		item.Attributes.DNSStatus = h.Data.Attributes.DNSStatus
+		item.Attributes.CertificateStatus = h.Data.Attributes.CertificateStatus
+		item.Links.Self = fmt.Sprintf("/v1/hosts/%s", h.Data.ID)
+
+		p.Data = append(p.Data, item)
+	}
+	p.Meta.HasMore = hasMore
+	p.Links = links("/v1/hosts", q, ids, start, end)
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleHost(w http.ResponseWriter, r *http.Request) {
+	id := trimID(r.URL.Path, "/v1/hosts/")
+
+	idx := s.hostIndex(id)
+	if idx == -1 {
+		notFound(w, "host", id)
+		return
+	}
+	host := s.hosts[idx]
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, host)
+	case http.MethodPatch:
+		attributes := hostAttributes{}
+		if err := decode(r, &attributes); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Unable to parse body: %s", err))
+			return
+		}
+
+		if errs := applyHost(host, attributes); len(errs) > 0 {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", errs...)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, host)
+	case http.MethodDelete:
+		// The real API refuses to remove a host that rules still redirect from.
+		for _, rule := range s.rules {
+			for _, h := range rule.Data.Relationships.SourceHosts.Data {
+				if h.ID == id {
+					writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", fieldError{
+						Resource: "host",
+						Param:    "id",
+						Code:     "in_use",
+						Message:  fmt.Sprintf("Host %s is a source host of rule %s.", host.Data.Attributes.Name, rule.Data.ID),
+					})
+					return
+				}
+			}
+		}
+
+		s.hosts = append(s.hosts[:idx], s.hosts[idx+1:]...)
+		writeRaw(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// applyHost validates every attribute before changing anything so that a
+// rejected update leaves the host untouched.
+func applyHost(host *easyredir.Host, attributes hostAttributes) (errs []fieldError) {
+	if n := attributes.NotFoundAction; n != nil && n.ResponseCode != nil {
+		valid := false
+		for _, v := range responseCodes {
+			if *n.ResponseCode == v {
+				valid = true
+			}
+		}
+		if !valid {
+			errs = append(errs, fieldError{
+				Resource: "host",
+				Param:    "not_found_action.response_code",
+				Code:     "invalid",
+				Message:  fmt.Sprintf("Response code must be one of %v.", responseCodes),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	a := &host.Data.Attributes
+
+	if m := attributes.MatchOptions; m != nil {
+		if m.CaseInsensitive != nil {
+			a.MatchOptions.CaseInsensitive = *m.CaseInsensitive
+		}
+		if m.SlashInsensitive != nil {
+			a.MatchOptions.SlashInsensitive = *m.SlashInsensitive
+		}
+	}
+
+	if sec := attributes.Security; sec != nil {
+		if sec.HTTPSUpgrade != nil {
+			a.Security.HTTPSUpgrade = *sec.HTTPSUpgrade
+		}
+		if sec.PreventForeignEmbedding != nil {
+			a.Security.PreventForeignEmbedding = *sec.PreventForeignEmbedding
+		}
+		if sec.HstsIncludeSubDomains != nil {
+			a.Security.HstsIncludeSubDomains = *sec.HstsIncludeSubDomains
+		}
+		if sec.HstsMaxAge != nil {
+			a.Security.HstsMaxAge = *sec.HstsMaxAge
+		}
+		if sec.HstsPreload != nil {
+			a.Security.HstsPreload = *sec.HstsPreload
+		}
+	}
+
+	if n := attributes.NotFoundAction; n != nil {
+		if n.ForwardParams != nil {
+			a.NotFoundAction.ForwardParams = *n.ForwardParams
+		}
+		if n.ForwardPath != nil {
+			a.NotFoundAction.ForwardPath = *n.ForwardPath
+		}
+		if n.Custom404Body != nil {
+			a.NotFoundAction.Custom404BodyPresent = *n.Custom404Body != ""
+		}
+		if n.ResponseCode != nil {
+			a.NotFoundAction.ResponseCode = *n.ResponseCode
+		}
+		if n.ResponseURL != nil {
+			a.NotFoundAction.ResponseURL = *n.ResponseURL
+		}
+	}
+
+	return nil
+}
+
+// hostFor returns the host with the given name, creating it with the API
+// defaults when it does not exist.
+func (s *Server) hostFor(name string) *easyredir.Host {
+	for _, h := range s.hosts {
+		if h.Data.Attributes.Name == name {
+			return h
+		}
+	}
+
+	host := &easyredir.Host{}
+	host.Data.ID = newID()
+	host.Data.Type = "host"
+
+	a := &host.Data.Attributes
+	a.Name = name
+	a.DNSStatus = "active"
+	a.CertificateStatus = "active"
+	a.MatchOptions.CaseInsensitive = false
+	a.MatchOptions.SlashInsensitive = false
+	a.Security.HTTPSUpgrade = false
+	a.Security.PreventForeignEmbedding = false
+	a.Security.HstsIncludeSubDomains = false
+	a.Security.HstsMaxAge = 0
+	a.Security.HstsPreload = false
+	a.NotFoundAction.ForwardParams = false
+	a.NotFoundAction.ForwardPath = false
+	a.NotFoundAction.ResponseCode = 404
+	a.DNSTestedAt = time.Now().UTC().Truncate(time.Second)
+
+	s.hosts = append(s.hosts, host)
+
+	return host
+}
+
+func (s *Server) hostIndex(id string) int {
+	for i, h := range s.hosts {
+		if h.Data.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
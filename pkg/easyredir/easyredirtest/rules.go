@@ -0,0 +1,352 @@
+package easyredirtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+var responseTypes = []string{"moved_permanently", "found"}
+
+type ruleAttributes struct {
+	ForwardParams *bool     `json:"forward_params"`
+	ForwardPath   *bool     `json:"forward_path"`
+	ResponseType  *string   `json:"response_type"`
+	SourceUrls    *[]string `json:"source_urls"`
+	TargetURL     *string   `json:"target_url"`
+}
+
+// AddRule stores a rule as if it had been created through the API, creating
+// any source hosts that do not exist yet. The stored rule is returned.
+func (s *Server) AddRule(r easyredir.Rule) (easyredir.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := r.Data.Attributes
+	rule, errs := s.createRule(ruleAttributes{
+		ForwardParams: &a.ForwardParams,
+		ForwardPath:   &a.ForwardPath,
+		ResponseType:  &a.ResponseType,
+		SourceUrls:    &a.SourceUrls,
+		TargetURL:     &a.TargetURL,
+	})
+	if len(errs) > 0 {
+		return easyredir.Rule{}, fmt.Errorf("AddRule: %s", errs[0].Message)
+	}
+
+	return *rule, nil
+}
+
+// Rules returns a copy of every stored rule.
+func (s *Server) Rules() (rules []easyredir.Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.rules {
+		rules = append(rules, *r)
+	}
+
+	return rules
+}
+
+func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listRules(w, r)
+	case http.MethodPost:
+		attributes := ruleAttributes{}
+		if err := decode(r, &attributes); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Unable to parse body: %s", err))
+			return
+		}
+
+		rule, errs := s.createRule(attributes)
+		if len(errs) > 0 {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", errs...)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, s.ruleDocument(rule))
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleRule(w http.ResponseWriter, r *http.Request) {
+	id := trimID(r.URL.Path, "/v1/rules/")
+
+	idx := s.ruleIndex(id)
+	if idx == -1 {
+		notFound(w, "rule", id)
+		return
+	}
+	rule := s.rules[idx]
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.ruleDocument(rule))
+	case http.MethodPatch:
+		attributes := ruleAttributes{}
+		if err := decode(r, &attributes); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Unable to parse body: %s", err))
+			return
+		}
+
+		updated := *rule
+		if errs := s.applyRule(&updated, attributes); len(errs) > 0 {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "Validation failed.", errs...)
+			return
+		}
+		*rule = updated
+
+		writeJSON(w, http.StatusOK, s.ruleDocument(rule))
+	case http.MethodDelete:
+		s.rules = append(s.rules[:idx], s.rules[idx+1:]...)
+		writeRaw(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) listRules(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sq := q.Get("sq")
+	tq := q.Get("tq")
+
+	matches := []*easyredir.Rule{}
+	ids := []string{}
+
+	for _, rule := range s.rules {
+		if tq != "" && !strings.Contains(rule.Data.Attributes.TargetURL, tq) {
+			continue
+		}
+
+		if sq != "" {
+			found := false
+			for _, u := range rule.Data.Attributes.SourceUrls {
+				if strings.Contains(u, sq) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		matches = append(matches, rule)
+		ids = append(ids, rule.Data.ID)
+	}
+
+	start, end, hasMore, err := paginate(q, ids)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	p := page{Data: []interface{}{}}
+	for _, rule := range matches[start:end] {
+		p.Data = append(p.Data, rule.Data)
+	}
+	p.Meta.HasMore = hasMore
+	p.Links = links("/v1/rules", q, ids, start, end)
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) createRule(attributes ruleAttributes) (*easyredir.Rule, []fieldError) {
+	rule := &easyredir.Rule{}
+	rule.Data.ID = newID()
+	rule.Data.Type = "rule"
+	rule.Data.Attributes.ResponseType = "moved_permanently"
+
+	if attributes.SourceUrls == nil {
+		attributes.SourceUrls = &[]string{}
+	}
+	if attributes.TargetURL == nil {
+		empty := ""
+		attributes.TargetURL = &empty
+	}
+
+	if errs := s.applyRule(rule, attributes); len(errs) > 0 {
+		return nil, errs
+	}
+
+	s.rules = append(s.rules, rule)
+
+	return rule, nil
+}
+
+// applyRule validates the attributes that are set and copies them onto the
+// rule, creating source hosts as the real API does.
+func (s *Server) applyRule(rule *easyredir.Rule, attributes ruleAttributes) (errs []fieldError) {
+	invalid := func(param string, code string, message string) {
+		errs = append(errs, fieldError{Resource: "rule", Param: param, Code: code, Message: message})
+	}
+
+	var sources []string
+
+	if attributes.SourceUrls != nil {
+		if len(*attributes.SourceUrls) == 0 {
+			invalid("source_urls", "missing", "Source URLs can't be blank.")
+		}
+
+		for _, u := range *attributes.SourceUrls {
+			n, err := normalizeSource(u)
+			if err != nil {
+				invalid("source_urls", "invalid", fmt.Sprintf("Source URL %s is invalid.", u))
+				continue
+			}
+
+			if owner := s.sourceOwner(n); owner != nil && owner.Data.ID != rule.Data.ID {
+				invalid("source_urls", "taken", fmt.Sprintf("Source URL %s is already used by rule %s.", u, owner.Data.ID))
+				continue
+			}
+
+			sources = append(sources, n)
+		}
+	}
+
+	if attributes.TargetURL != nil {
+		if u, err := url.Parse(*attributes.TargetURL); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("target_url", "invalid", "Target URL must be an absolute URL.")
+		}
+	}
+
+	if attributes.ResponseType != nil {
+		valid := false
+		for _, v := range responseTypes {
+			if *attributes.ResponseType == v {
+				valid = true
+			}
+		}
+		if !valid {
+			invalid("response_type", "invalid", fmt.Sprintf("Response type must be one of %s.", strings.Join(responseTypes, ", ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	a := &rule.Data.Attributes
+
+	if attributes.ForwardParams != nil {
+		a.ForwardParams = *attributes.ForwardParams
+	}
+	if attributes.ForwardPath != nil {
+		a.ForwardPath = *attributes.ForwardPath
+	}
+	if attributes.ResponseType != nil {
+		a.ResponseType = *attributes.ResponseType
+	}
+	if attributes.TargetURL != nil {
+		a.TargetURL = *attributes.TargetURL
+	}
+
+	if attributes.SourceUrls != nil {
+		a.SourceUrls = sources
+
+		rel := &rule.Data.Relationships.SourceHosts
+		rel.Data = nil
+		rel.Links.Related = fmt.Sprintf("/v1/rules/%s/hosts", rule.Data.ID)
+
+		seen := make(map[string]bool)
+		for _, u := range sources {
+			host := s.hostFor(hostname(u))
+			if seen[host.Data.ID] {
+				continue
+			}
+			seen[host.Data.ID] = true
+
+			rel.Data = append(rel.Data, struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+			}{ID: host.Data.ID, Type: "host"})
+		}
+	}
+
+	return nil
+}
+
+// ruleDocument includes the source hosts alongside the rule, as the API does
+// for single rule responses.
+func (s *Server) ruleDocument(rule *easyredir.Rule) interface{} {
+	included := []interface{}{}
+	for _, h := range rule.Data.Relationships.SourceHosts.Data {
+		if i := s.hostIndex(h.ID); i != -1 {
+			included = append(included, s.hosts[i].Data)
+		}
+	}
+
+	return map[string]interface{}{
+		"data":     rule.Data,
+		"included": included,
+	}
+}
+
+func (s *Server) ruleIndex(id string) int {
+	for i, r := range s.rules {
+		if r.Data.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Server) sourceOwner(source string) *easyredir.Rule {
+	for _, r := range s.rules {
+		for _, u := range r.Data.Attributes.SourceUrls {
+			if u == source {
+				return r
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizeSource lowercases the host and adds the trailing slash the API
+// appends to source URLs without a path.
+func normalizeSource(source string) (string, error) {
+	scheme := ""
+	raw := source
+	if i := strings.Index(source, "://"); i != -1 {
+		scheme = source[:i+3]
+		raw = source[i+3:]
+	}
+
+	u, err := url.Parse("http://" + raw)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid source URL: %s", source)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	n := scheme + strings.ToLower(u.Host) + path
+	if u.RawQuery != "" {
+		n += "?" + u.RawQuery
+	}
+
+	return n, nil
+}
+
+func hostname(source string) string {
+	if i := strings.Index(source, "://"); i != -1 {
+		source = source[i+3:]
+	}
+
+	u, err := url.Parse("http://" + source)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
@@ -0,0 +1,329 @@
+// Package easyredirtest provides an in-memory EasyRedir API for tests and
+// offline development.
+package easyredirtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+)
+
+const (
+	defaultLimit int = 25
+	maxLimit     int = 100
+
+	defaultRateLimit       int           = 1000
+	defaultRateLimitWindow time.Duration = time.Hour
+)
+
+// Server is a fake EasyRedir API. Rules and hosts are kept in insertion
+// order, which is also the order they are paginated in.
+type Server struct {
+	*httptest.Server
+
+	// Key and Secret are compared against the basic auth credentials of each
+	// request. When empty, any non-empty credentials are accepted.
+	Key    string
+	Secret string
+
+	// RateLimit is the number of requests allowed per RateLimitWindow. Once
+	// exhausted, requests are answered with 429 until the window resets.
+	RateLimit       int
+	RateLimitWindow time.Duration
+
+	mu          sync.Mutex
+	rules       []*easyredir.Rule
+	hosts       []*easyredir.Host
+	idempotency map[string]response
+	remaining   int
+	reset       time.Time
+	failures    []int
+	requests    int
+}
+
+type response struct {
+	status int
+	body   []byte
+}
+
+type errorResponse struct {
+	Type    string       `json:"type"`
+	Message string       `json:"message"`
+	Errors  []fieldError `json:"errors,omitempty"`
+}
+
+type fieldError struct {
+	Resource string `json:"resource"`
+	Param    string `json:"param"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+type page struct {
+	Data  []interface{}   `json:"data"`
+	Meta  easyredir.Meta  `json:"meta"`
+	Links easyredir.Links `json:"links"`
+}
+
+// NewServer starts a fake API. Callers must Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		RateLimit:       defaultRateLimit,
+		RateLimitWindow: defaultRateLimitWindow,
+		idempotency:     make(map[string]response),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/rules", s.handleRules)
+	mux.HandleFunc("/v1/rules/", s.handleRule)
+	mux.HandleFunc("/v1/hosts", s.handleHosts)
+	mux.HandleFunc("/v1/hosts/", s.handleHost)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+
+	return s
+}
+
+// BaseURL is the versioned API root to point a client at.
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// FailNext makes the next requests fail with the given status codes, in
+// order, before any other processing. Useful for exercising retries.
+func (s *Server) FailNext(status ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, status...)
+}
+
+// Requests returns the number of requests received, including failed ones.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests++
+
+		s.rateLimit(w)
+
+		if len(s.failures) > 0 {
+			status := s.failures[0]
+			s.failures = s.failures[1:]
+			writeError(w, status, "api_error", http.StatusText(status))
+			return
+		}
+
+		key, secret, ok := r.BasicAuth()
+		if !ok || key == "" || secret == "" || (s.Key != "" && (key != s.Key || secret != s.Secret)) {
+			writeError(w, http.StatusUnauthorized, "authentication_error", "Invalid API key or secret.")
+			return
+		}
+
+		if s.remaining < 0 {
+			writeError(w, http.StatusTooManyRequests, "rate_limit_error", "Too many requests.")
+			return
+		}
+
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" || r.Method == http.MethodGet || r.Method == http.MethodDelete {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if res, ok := s.idempotency[idempotencyKey]; ok {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeRaw(w, res.status, res.body)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		if rec.Code < http.StatusInternalServerError {
+			s.idempotency[idempotencyKey] = response{status: rec.Code, body: rec.Body.Bytes()}
+		}
+
+		writeRaw(w, rec.Code, rec.Body.Bytes())
+	})
+}
+
+// rateLimit decrements the quota and sets the headers the real API sends. A
+// negative remaining count marks the request as over the limit.
+func (s *Server) rateLimit(w http.ResponseWriter) {
+	now := time.Now()
+
+	if s.reset.IsZero() || !now.Before(s.reset) {
+		s.remaining = s.RateLimit
+		s.reset = now.Add(s.RateLimitWindow)
+	}
+
+	s.remaining--
+
+	remaining := s.remaining
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+}
+
+// paginate applies the limit, starting_after and ending_before parameters to
+// a list of IDs and returns the selected index range.
+func paginate(q url.Values, ids []string) (start int, end int, hasMore bool, err error) {
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, false, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	index := func(id string) int {
+		for i, v := range ids {
+			if v == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if v := q.Get("ending_before"); v != "" {
+		end = index(v)
+		if end == -1 {
+			return 0, 0, false, fmt.Errorf("ending_before %s not found", v)
+		}
+
+		start = end - limit
+		if start < 0 {
+			start = 0
+		}
+
+		return start, end, start > 0, nil
+	}
+
+	if v := q.Get("starting_after"); v != "" {
+		i := index(v)
+		if i == -1 {
+			return 0, 0, false, fmt.Errorf("starting_after %s not found", v)
+		}
+		start = i + 1
+	}
+
+	end = start + limit
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	return start, end, end < len(ids), nil
+}
+
+// links builds the next and prev links for a page, preserving any filters.
+func links(path string, q url.Values, ids []string, start int, end int) (l easyredir.Links) {
+	link := func(key string, id string) string {
+		v := url.Values{}
+		for _, k := range []string{"limit", "sq", "tq"} {
+			if q.Get(k) != "" {
+				v.Set(k, q.Get(k))
+			}
+		}
+		v.Set(key, id)
+		return fmt.Sprintf("%s?%s", path, v.Encode())
+	}
+
+	if end < len(ids) && end > start {
+		l.Next = link("starting_after", ids[end-1])
+	}
+	if start > 0 && end > start {
+		l.Prev = link("ending_before", ids[start])
+	}
+
+	return l
+}
+
+// decode accepts both bare attributes and a JSON:API document wrapping them
+// in data.attributes.
+func decode(r *http.Request, v interface{}) error {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		return err
+	}
+
+	doc := struct {
+		Data *struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}{}
+
+	if err := json.Unmarshal(buf.Bytes(), &doc); err == nil && doc.Data != nil && doc.Data.Attributes != nil {
+		return json.Unmarshal(doc.Data.Attributes, v)
+	}
+
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	writeRaw(w, status, body)
+}
+
+func writeRaw(w http.ResponseWriter, status int, body []byte) {
+	if len(body) > 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, status int, typ string, message string, errs ...fieldError) {
+	writeJSON(w, status, errorResponse{
+		Type:    typ,
+		Message: message,
+		Errors:  errs,
+	})
+}
+
+func notFound(w http.ResponseWriter, resource string, id string) {
+	writeError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such %s: %s", resource, id), fieldError{
+		Resource: resource,
+		Param:    "id",
+		Code:     "not_found",
+		Message:  fmt.Sprintf("No such %s: %s", resource, id),
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed.")
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+func trimID(path string, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(path, prefix), "/")
+}
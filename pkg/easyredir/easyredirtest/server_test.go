@@ -0,0 +1,164 @@
+package easyredirtest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func TestServer(t *testing.T) {
+	rule := `{"source_urls": ["old.com"], "target_url": "https://new.com"}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		key         string
+		idempotency string
+		rateLimit   int
+		repeat      int
+		wantStatus  int
+		wantRules   int
+		wantReplay  bool
+	}{
+		{
+			name:       "create rule",
+			method:     http.MethodPost,
+			path:       "/rules",
+			body:       rule,
+			wantStatus: http.StatusCreated,
+			wantRules:  1,
+		},
+		{
+			name:       "source already used",
+			method:     http.MethodPost,
+			path:       "/rules",
+			body:       rule,
+			repeat:     1,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantRules:  1,
+		},
+		{
+			name:        "idempotent replay",
+			method:      http.MethodPost,
+			path:        "/rules",
+			body:        rule,
+			idempotency: "create-old",
+			repeat:      1,
+			wantStatus:  http.StatusCreated,
+			wantRules:   1,
+			wantReplay:  true,
+		},
+		{
+			name:       "invalid response type",
+			method:     http.MethodPost,
+			path:       "/rules",
+			body:       `{"source_urls": ["old.com"], "target_url": "https://new.com", "response_type": "gone"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "relative target",
+			method:     http.MethodPost,
+			path:       "/rules",
+			body:       `{"source_urls": ["old.com"], "target_url": "/new"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "wrong credentials",
+			method:     http.MethodGet,
+			path:       "/rules",
+			key:        "other",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rate limited",
+			method:     http.MethodGet,
+			path:       "/rules",
+			rateLimit:  1,
+			repeat:     1,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "invalid limit",
+			method:     http.MethodGet,
+			path:       "/rules?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown rule",
+			method:     http.MethodGet,
+			path:       "/rules/missing",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := easyredirtest.NewServer()
+			t.Cleanup(s.Close)
+
+			s.Key, s.Secret = "key", "secret"
+			if tt.rateLimit > 0 {
+				s.RateLimit = tt.rateLimit
+			}
+
+			key := tt.key
+			if key == "" {
+				key = "key"
+			}
+
+			var res *http.Response
+			for i := 0; i <= tt.repeat; i++ {
+				req, err := http.NewRequest(tt.method, s.BaseURL()+tt.path, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatalf("NewRequest: %v", err)
+				}
+				req.SetBasicAuth(key, "secret")
+				req.Header.Set("Content-Type", "application/json")
+				if tt.idempotency != "" {
+					req.Header.Set("Idempotency-Key", tt.idempotency)
+				}
+
+				if res, err = http.DefaultClient.Do(req); err != nil {
+					t.Fatalf("Do: %v", err)
+				}
+				res.Body.Close()
+			}
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := len(s.Rules()); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := res.Header.Get("Idempotent-Replayed") == "true"; got != tt.wantReplay {
+				t.Errorf("replayed = %t, want %t", got, tt.wantReplay)
+			}
+			if res.Header.Get("X-RateLimit-Limit") == "" {
+				t.Errorf("rate limit headers not set")
+			}
+		})
+	}
+}
+
+func TestMustAddRule(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+
+	rule := s.MustAddRule(t, easyredirtest.NewRule("https://new.com", "old.com", "www.old.com"))
+
+	if got := len(s.Hosts()); got != 2 {
+		t.Errorf("hosts = %d, want 2", got)
+	}
+
+	rules, err := c.ListRules(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListRules: %v", err)
+	}
+	if len(rules.Data) != 1 || rules.Data[0].ID != rule.Data.ID {
+		t.Errorf("rules = %+v, want %s", rules.Data, rule.Data.ID)
+	}
+}
@@ -0,0 +1,97 @@
+package easyredir
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned for any non-2xx response. Use errors.As to inspect it
+// or the Is helpers to branch on the common cases.
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Type       string       `json:"type"`
+	Message    string       `json:"message"`
+	Errors     []FieldError `json:"errors"`
+	RateLimit  RateLimit    `json:"-"`
+}
+
+type FieldError struct {
+	Resource string `json:"resource"`
+	Param    string `json:"param"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// RateLimit is the quota reported alongside a response. Zero values mean the
+// headers were absent.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	s := fmt.Sprintf("api error: status code: %d", e.StatusCode)
+	if e.Type != "" {
+		s += fmt.Sprintf(" type: %s", e.Type)
+	}
+	s += fmt.Sprintf(" message: %s", msg)
+
+	if len(e.Errors) > 0 {
+		fields := []string{}
+		for _, f := range e.Errors {
+			fields = append(fields, fmt.Sprintf("%s.%s: %s", f.Resource, f.Param, f.Message))
+		}
+		s += fmt.Sprintf(" errors: %s", strings.Join(fields, "; "))
+	}
+
+	return s
+}
+
+// IsNotFound reports whether err is an API error for a missing resource.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsRateLimited reports whether err is an API error caused by exhausting the
+// rate limit, after any retries.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsValidation reports whether err is an API error rejecting the submitted
+// attributes.
+func IsValidation(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.StatusCode == http.StatusUnprocessableEntity || (e.StatusCode == http.StatusBadRequest && len(e.Errors) > 0)
+}
+
+func hasStatus(err error, status int) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.StatusCode == status
+}
+
+func rateLimitFromResponse(res *http.Response) (rl RateLimit) {
+	rl.Limit, _ = strconv.Atoi(res.Header.Get("X-RateLimit-Limit"))
+	rl.Remaining, _ = strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	rl.Reset = parseReset(res.Header.Get("X-RateLimit-Reset"), time.Now())
+
+	return rl
+}
@@ -2,10 +2,10 @@ package easyredir
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
 	"text/template"
 	"time"
@@ -21,51 +21,54 @@ import (
 var hostPrintTemplate string
 
 type Host struct {
-	Data struct {
-		ID         string `json:"id"`
-		Type       string `json:"type"`
-		Attributes struct {
-			Name              string `json:"name"`
-			DNSStatus         string `json:"dns_status"`
-			CertificateStatus string `json:"certificate_status"`
-			MatchOptions      struct {
-				CaseInsensitive  interface{} `json:"case_insensitive,omitempty"`
-				SlashInsensitive interface{} `json:"slash_insensitive,omitempty"`
-			} `json:"match_options"`
-			Security struct {
-				HTTPSUpgrade            interface{} `json:"https_upgrade,omitempty"`
-				PreventForeignEmbedding interface{} `json:"prevent_foreign_embedding,omitempty"`
-				HstsIncludeSubDomains   interface{} `json:"hsts_include_sub_domains,omitempty"`
-				HstsMaxAge              interface{} `json:"hsts_max_age,omitempty"`
-				HstsPreload             interface{} `json:"hsts_preload,omitempty"`
-			} `json:"security"`
-			NotFoundAction struct {
-				ForwardParams        interface{} `json:"forward_params,omitempty"`
-				ForwardPath          interface{} `json:"forward_path,omitempty"`
-				Custom404BodyPresent bool        `json:"custom_404_body_present,omitempty"`
-				Custom404Body        string      `json:"custom_404_body,omitempty"`
-				ResponseCode         int         `json:"response_code,omitempty"`
-				ResponseURL          interface{} `json:"response_url,omitempty"`
-			} `json:"not_found_action"`
-			AcmeEnabled        bool `json:"acme_enabled"`
-			DetectedDNSEntries []struct {
+	Data HostData `json:"data"`
+}
+
+// HostData is a single host resource. Rules include it for their source hosts.
+type HostData struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name              string `json:"name"`
+		DNSStatus         string `json:"dns_status"`
+		CertificateStatus string `json:"certificate_status"`
+		MatchOptions      struct {
+			CaseInsensitive  interface{} `json:"case_insensitive,omitempty"`
+			SlashInsensitive interface{} `json:"slash_insensitive,omitempty"`
+		} `json:"match_options"`
+		Security struct {
+			HTTPSUpgrade            interface{} `json:"https_upgrade,omitempty"`
+			PreventForeignEmbedding interface{} `json:"prevent_foreign_embedding,omitempty"`
+			HstsIncludeSubDomains   interface{} `json:"hsts_include_sub_domains,omitempty"`
+			HstsMaxAge              interface{} `json:"hsts_max_age,omitempty"`
+			HstsPreload             interface{} `json:"hsts_preload,omitempty"`
+		} `json:"security"`
+		NotFoundAction struct {
+			ForwardParams        interface{} `json:"forward_params,omitempty"`
+			ForwardPath          interface{} `json:"forward_path,omitempty"`
+			Custom404BodyPresent bool        `json:"custom_404_body_present,omitempty"`
+			Custom404Body        string      `json:"custom_404_body,omitempty"`
+			ResponseCode         int         `json:"response_code,omitempty"`
+			ResponseURL          interface{} `json:"response_url,omitempty"`
+		} `json:"not_found_action"`
+		AcmeEnabled        bool `json:"acme_enabled"`
+		DetectedDNSEntries []struct {
+			Type   string   `json:"type"`
+			Values []string `json:"values"`
+		} `json:"detected_dns_entries"`
+		DNSTestedAt        time.Time `json:"dns_tested_at"`
+		RequiredDNSEntries struct {
+			Recommended struct {
 				Type   string   `json:"type"`
 				Values []string `json:"values"`
-			} `json:"detected_dns_entries"`
-			DNSTestedAt        time.Time `json:"dns_tested_at"`
-			RequiredDNSEntries struct {
-				Recommended struct {
-					Type   string   `json:"type"`
-					Values []string `json:"values"`
-				} `json:"recommended"`
-				Alternatives []struct {
-					Type   string   `json:"type"`
-					Values []string `json:"values"`
-				} `json:"alternatives"`
-			} `json:"required_dns_entries"`
-		} `json:"attributes"`
-		Links struct{} `json:"links"`
-	} `json:"data"`
+			} `json:"recommended"`
+			Alternatives []struct {
+				Type   string   `json:"type"`
+				Values []string `json:"values"`
+			} `json:"alternatives"`
+		} `json:"required_dns_entries"`
+	} `json:"attributes"`
+	Links struct{} `json:"links"`
 }
 
 type Hosts struct {
@@ -91,51 +94,29 @@ type HostsOptions struct {
 	EndingBefore  string `json:"ending_before"`
 }
 
-func (c *Client) ListHosts(options *HostsOptions) (hosts Hosts, err error) {
-	limit := 100
-
-	var startingAfter string
-	var endingBefore string
-
-	for {
-		res := Hosts{}
-
-		req, err := http.NewRequest("GET", fmt.Sprintf("%s/hosts?limit=%d&starting_after=%s&ending_before=%s", c.baseURL, limit, startingAfter, endingBefore), nil)
-		if err != nil {
-			return hosts, fmt.Errorf("ListHosts: unable to create request: %w", err)
-		}
-
-		if err = c.sendRequest(req, &res); err != nil {
-			return hosts, fmt.Errorf("ListHosts: unable to send request: %w", err)
-		}
-
-		hosts.Data = append(hosts.Data, res.Data...)
+// ListHosts collects every page into memory. Use Hosts to stream pages
+// instead.
+func (c *Client) ListHosts(ctx context.Context, options *HostsOptions) (hosts Hosts, err error) {
+	p := c.Hosts(ctx, options)
 
-		if res.Meta.HasMore == false {
-			break
+	for p.Next() {
+		page := p.Page()
+		if p.Backward() {
+			hosts.Data = append(page.Data, hosts.Data...)
+			continue
 		}
+		hosts.Data = append(hosts.Data, page.Data...)
+	}
 
-		if res.Links.Next != "" {
-			u, err := url.Parse(res.Links.Next)
-			if err != nil {
-				panic(err)
-			}
-			startingAfter = u.Query().Get("starting_after")
-		}
-		// if res.Links.Prev != "" {
-		// 	u, err := url.Parse(res.Links.Prev)
-		// 	if err != nil {
-		// 		panic(err)
-		// 	}
-		// 	endingBefore = u.Query().Get("ending_before")
-		// }
+	if err = p.Err(); err != nil {
+		return hosts, fmt.Errorf("ListHosts: %w", err)
 	}
 
 	return hosts, nil
 }
 
-func (c *Client) GetHost(host *Host) (err error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/hosts/%s", c.baseURL, host.Data.ID), nil)
+func (c *Client) GetHost(ctx context.Context, host *Host) (err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/hosts/%s", c.baseURL, host.Data.ID), nil)
 	if err != nil {
 		return fmt.Errorf("GetHost: unable to create request: %w", err)
 	}
@@ -147,7 +128,7 @@ func (c *Client) GetHost(host *Host) (err error) {
 	return nil
 }
 
-func (c *Client) UpdateHost(h *Host) (host *Host, err error) {
+func (c *Client) UpdateHost(ctx context.Context, h *Host) (host *Host, err error) {
 	var buf bytes.Buffer
 
 	err = json.NewEncoder(&buf).Encode(h.Data.Attributes)
@@ -155,7 +136,7 @@ func (c *Client) UpdateHost(h *Host) (host *Host, err error) {
 		return nil, fmt.Errorf("UpdateHost: unable to encode attributes: %w", err)
 	}
 
-	req, err := http.NewRequest("PATCH", fmt.Sprintf("%s/hosts/%s", c.baseURL, h.Data.ID), &buf)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", fmt.Sprintf("%s/hosts/%s", c.baseURL, h.Data.ID), &buf)
 	if err != nil {
 		return nil, fmt.Errorf("UpdateHost: unable to create request: %w", err)
 	}
@@ -167,16 +148,42 @@ func (c *Client) UpdateHost(h *Host) (host *Host, err error) {
 	return host, nil
 }
 
+func (c *Client) CreateHost(ctx context.Context, h *Host) (host *Host, err error) {
+	var buf bytes.Buffer
+
+	err = json.NewEncoder(&buf).Encode(h.Data.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("CreateHost: unable to encode attributes: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/hosts", c.baseURL), &buf)
+	if err != nil {
+		return nil, fmt.Errorf("CreateHost: unable to create request: %w", err)
+	}
+
+	if err = c.sendRequest(req, &host); err != nil {
+		return nil, fmt.Errorf("CreateHost: unable to send request: %w", err)
+	}
+
+	return host, nil
+}
+
+func (c *Client) RemoveHost(ctx context.Context, h *Host) (host *Host, err error) {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/hosts/%s", c.baseURL, h.Data.ID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("RemoveHost: unable to create request: %w", err)
+	}
+
+	if err = c.sendRequest(req, &host); err != nil {
+		return nil, fmt.Errorf("RemoveHost: unable to send request: %w", err)
+	}
+
+	return host, nil
+}
+
 func (r *Hosts) Print() {
-	t := table.NewWriter()
-
-	t.SetStyle(table.StyleColoredBright)
-	t.Style().Options.DrawBorder = false
-	t.Style().Color = table.ColorOptions{}
-	t.Style().Box.PaddingLeft = ""
-	t.Style().Box.PaddingRight = "    "
-	t.Style().Color.Header = text.Colors{text.Bold}
-	t.SetOutputMirror(os.Stdout)
+	t := newTable(os.Stdout)
+
 	t.AppendHeader(table.Row{"ID", "NAME", "DNS STATUS", "CERTIFICATE STATUS"})
 	for _, h := range r.Data {
 		t.AppendRow(table.Row{h.ID, h.Attributes.Name, h.Attributes.DNSStatus, h.Attributes.CertificateStatus})
@@ -201,3 +208,37 @@ func (r *Host) Print() {
 
 	return
 }
+
+func (r *Hosts) columns() []string {
+	return []string{"ID", "TYPE", "NAME", "DNS STATUS", "CERTIFICATE STATUS", "LINK"}
+}
+
+func (r *Hosts) rows(sep string) (rows [][]string) {
+	for _, h := range r.Data {
+		rows = append(rows, []string{h.ID, h.Type, h.Attributes.Name, h.Attributes.DNSStatus, h.Attributes.CertificateStatus, h.Links.Self})
+	}
+
+	return rows
+}
+
+func (r *Host) columns() []string {
+	return []string{
+		"ID", "NAME", "DNS STATUS", "CERTIFICATE STATUS",
+		"CASE INSENSITIVE", "SLASH INSENSITIVE",
+		"HTTPS UPGRADE", "PREVENT FOREIGN EMBEDDING", "HSTS INCLUDE SUB DOMAINS", "HSTS MAX AGE", "HSTS PRELOAD",
+		"NOT FOUND FORWARD PARAMS", "NOT FOUND FORWARD PATH", "NOT FOUND CUSTOM 404 BODY PRESENT", "NOT FOUND RESPONSE CODE", "NOT FOUND RESPONSE URL",
+		"ACME ENABLED", "DNS TESTED AT",
+	}
+}
+
+func (r *Host) rows(sep string) [][]string {
+	a := r.Data.Attributes
+
+	return [][]string{{
+		r.Data.ID, a.Name, a.DNSStatus, a.CertificateStatus,
+		cell(a.MatchOptions.CaseInsensitive), cell(a.MatchOptions.SlashInsensitive),
+		cell(a.Security.HTTPSUpgrade), cell(a.Security.PreventForeignEmbedding), cell(a.Security.HstsIncludeSubDomains), cell(a.Security.HstsMaxAge), cell(a.Security.HstsPreload),
+		cell(a.NotFoundAction.ForwardParams), cell(a.NotFoundAction.ForwardPath), cell(a.NotFoundAction.Custom404BodyPresent), cell(a.NotFoundAction.ResponseCode), cell(a.NotFoundAction.ResponseURL),
+		cell(a.AcmeEnabled), a.DNSTestedAt.Format(time.RFC3339),
+	}}
+}
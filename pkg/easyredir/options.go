@@ -0,0 +1,52 @@
+package easyredir
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures a Client created by NewClient.
+type Option func(c *Client)
+
+// WithBaseURL points the client at another API root, such as a proxy or the
+// fake server in easyredirtest.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = u
+	}
+}
+
+func WithAPIKey(key string, secret string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+		c.apiSecret = secret
+	}
+}
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = hc
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+func WithLogger(l zerolog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithTimeout limits each HTTP request, including reading the response. It
+// applies to the HTTP client regardless of the option order.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
@@ -0,0 +1,167 @@
+package easyredir
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/template"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputDefault  string = ""
+	OutputJSON     string = "json"
+	OutputYAML     string = "yaml"
+	OutputCSV      string = "csv"
+	OutputWide     string = "wide"
+	OutputTemplate string = "template"
+)
+
+var OutputFormats = []string{OutputJSON, OutputYAML, OutputCSV, OutputWide, OutputTemplate}
+
+type OutputOptions struct {
+	Format   string
+	Template string
+	Writer   io.Writer
+}
+
+// tabular is implemented by every resource that can be rendered as CSV or a
+// wide table. Rows contain every attribute, with multiple values in a single
+// cell joined by sep.
+type tabular interface {
+	Print()
+	columns() []string
+	rows(sep string) [][]string
+}
+
+func (r *Host) Output(o *OutputOptions) error {
+	return output(r, o)
+}
+
+func (r *Hosts) Output(o *OutputOptions) error {
+	return output(r, o)
+}
+
+func (r *Rule) Output(o *OutputOptions) error {
+	return output(r, o)
+}
+
+func (r *Rules) Output(o *OutputOptions) error {
+	return output(r, o)
+}
+
+func output(v tabular, o *OutputOptions) (err error) {
+	if o == nil {
+		o = &OutputOptions{}
+	}
+
+	w := o.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+
+	switch o.Format {
+	case OutputDefault:
+		v.Print()
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(v); err != nil {
+			return fmt.Errorf("output: unable to encode JSON: %w", err)
+		}
+	case OutputYAML:
+		// Round trip through JSON so that keys match the API field names.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("output: unable to encode JSON: %w", err)
+		}
+
+		var ms yaml.MapSlice
+		if err = yaml.Unmarshal(data, &ms); err != nil {
+			return fmt.Errorf("output: unable to decode JSON: %w", err)
+		}
+
+		out, err := yaml.Marshal(ms)
+		if err != nil {
+			return fmt.Errorf("output: unable to encode YAML: %w", err)
+		}
+
+		if _, err = w.Write(out); err != nil {
+			return fmt.Errorf("output: unable to write YAML: %w", err)
+		}
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(v.columns())
+		cw.WriteAll(v.rows(" "))
+		if err = cw.Error(); err != nil {
+			return fmt.Errorf("output: unable to write CSV: %w", err)
+		}
+	case OutputWide:
+		t := newTable(w)
+
+		header := table.Row{}
+		for _, c := range v.columns() {
+			header = append(header, c)
+		}
+		t.AppendHeader(header)
+
+		for _, r := range v.rows("\n") {
+			row := table.Row{}
+			for _, c := range r {
+				row = append(row, c)
+			}
+			t.AppendRow(row)
+		}
+		t.Render()
+	case OutputTemplate:
+		if o.Template == "" {
+			return fmt.Errorf("output: missing template")
+		}
+
+		t, err := template.New("").Parse(o.Template)
+		if err != nil {
+			return fmt.Errorf("output: unable to parse template: %w", err)
+		}
+
+		if err = t.Execute(w, v); err != nil {
+			return fmt.Errorf("output: unable to execute template: %w", err)
+		}
+	default:
+		return fmt.Errorf("output: unknown format: %s", o.Format)
+	}
+
+	return nil
+}
+
+func newTable(w io.Writer) table.Writer {
+	t := table.NewWriter()
+
+	t.SetStyle(table.StyleColoredBright)
+	t.Style().Options.DrawBorder = false
+	t.Style().Color = table.ColorOptions{}
+	t.Style().Box.PaddingLeft = ""
+	t.Style().Box.PaddingRight = "    "
+	t.Style().Color.Header = text.Colors{text.Bold}
+	t.SetOutputMirror(w)
+
+	return t
+}
+
+// cell formats the loosely typed attributes the API returns. Numbers are
+// decoded as float64 and would otherwise print in exponent form.
+func cell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,232 @@
+package easyredir
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultPageLimit int = 100
+)
+
+// paginator holds the cursor state shared by the rule and host paginators.
+// Paging runs forwards with starting_after, or backwards with ending_before
+// when only EndingBefore is set.
+type paginator struct {
+	ctx           context.Context
+	client        *Client
+	limit         int
+	startingAfter string
+	endingBefore  string
+	backward      bool
+	done          bool
+	err           error
+}
+
+func newPaginator(ctx context.Context, c *Client, limit int, startingAfter string, endingBefore string) paginator {
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	return paginator{
+		ctx:           ctx,
+		client:        c,
+		limit:         limit,
+		startingAfter: startingAfter,
+		endingBefore:  endingBefore,
+		backward:      startingAfter == "" && endingBefore != "",
+	}
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *paginator) Err() error {
+	return p.err
+}
+
+// Backward reports whether pages are returned in reverse order.
+func (p *paginator) Backward() bool {
+	return p.backward
+}
+
+func (p *paginator) query() url.Values {
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(p.limit))
+
+	if p.startingAfter != "" {
+		q.Set("starting_after", p.startingAfter)
+	}
+	if p.endingBefore != "" {
+		q.Set("ending_before", p.endingBefore)
+	}
+
+	return q
+}
+
+func (p *paginator) get(path string, q url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(p.ctx, "GET", fmt.Sprintf("%s/%s?%s", p.client.baseURL, path, q.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	if err = p.client.sendRequest(req, v); err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+
+	return nil
+}
+
+// advance moves the cursor past the page just fetched. The cursor is taken
+// from the page links, falling back to the first or last ID on the page.
+func (p *paginator) advance(meta Meta, links Links, first string, last string) error {
+	if !meta.HasMore || first == "" {
+		p.done = true
+		return nil
+	}
+
+	link, key, fallback := links.Next, "starting_after", last
+	if p.backward {
+		link, key, fallback = links.Prev, "ending_before", first
+	}
+
+	cursor := fallback
+
+	if link != "" {
+		u, err := url.Parse(link)
+		if err != nil {
+			return fmt.Errorf("unable to parse link %q: %w", link, err)
+		}
+
+		if v := u.Query().Get(key); v != "" {
+			cursor = v
+		}
+	}
+
+	if p.backward {
+		p.startingAfter, p.endingBefore = "", cursor
+	} else {
+		p.startingAfter, p.endingBefore = cursor, ""
+	}
+
+	return nil
+}
+
+// RulesPaginator fetches rules one page at a time. Call Next until it
+// returns false, then check Err.
+//
+//	p := c.Rules(ctx, &easyredir.RulesOptions{})
+//	for p.Next() {
+//		for _, r := range p.Page().Data {
+//			...
+//		}
+//	}
+//	if err := p.Err(); err != nil {
+//		...
+//	}
+type RulesPaginator struct {
+	paginator
+	sourceURL string
+	targetURL string
+	page      Rules
+}
+
+func (c *Client) Rules(ctx context.Context, options *RulesOptions) *RulesPaginator {
+	if options == nil {
+		options = &RulesOptions{}
+	}
+
+	return &RulesPaginator{
+		paginator: newPaginator(ctx, c, options.Limit, options.StartingAfter, options.EndingBefore),
+		sourceURL: options.SourceURL,
+		targetURL: options.TargetURL,
+	}
+}
+
+// Next fetches the next page and reports whether one was available.
+func (p *RulesPaginator) Next() bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	q := p.query()
+	if p.sourceURL != "" {
+		q.Set("sq", p.sourceURL)
+	}
+	if p.targetURL != "" {
+		q.Set("tq", p.targetURL)
+	}
+
+	res := Rules{}
+	if err := p.get("rules", q, &res); err != nil {
+		p.err = fmt.Errorf("Rules: %w", err)
+		return false
+	}
+
+	if len(res.Data) == 0 {
+		p.done = true
+		return false
+	}
+
+	if err := p.advance(res.Meta, res.Links, res.Data[0].ID, res.Data[len(res.Data)-1].ID); err != nil {
+		p.err = fmt.Errorf("Rules: %w", err)
+	}
+
+	p.page = res
+
+	return true
+}
+
+// Page returns the page fetched by the last call to Next.
+func (p *RulesPaginator) Page() Rules {
+	return p.page
+}
+
+// HostsPaginator fetches hosts one page at a time, in the same way as
+// RulesPaginator.
+type HostsPaginator struct {
+	paginator
+	page Hosts
+}
+
+func (c *Client) Hosts(ctx context.Context, options *HostsOptions) *HostsPaginator {
+	if options == nil {
+		options = &HostsOptions{}
+	}
+
+	return &HostsPaginator{
+		paginator: newPaginator(ctx, c, options.Limit, options.StartingAfter, options.EndingBefore),
+	}
+}
+
+// Next fetches the next page and reports whether one was available.
+func (p *HostsPaginator) Next() bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	res := Hosts{}
+	if err := p.get("hosts", p.query(), &res); err != nil {
+		p.err = fmt.Errorf("Hosts: %w", err)
+		return false
+	}
+
+	if len(res.Data) == 0 {
+		p.done = true
+		return false
+	}
+
+	if err := p.advance(res.Meta, res.Links, res.Data[0].ID, res.Data[len(res.Data)-1].ID); err != nil {
+		p.err = fmt.Errorf("Hosts: %w", err)
+	}
+
+	p.page = res
+
+	return true
+}
+
+// Page returns the page fetched by the last call to Next.
+func (p *HostsPaginator) Page() Hosts {
+	return p.page
+}
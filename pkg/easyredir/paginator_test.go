@@ -0,0 +1,126 @@
+package easyredir_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func TestListRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		startingAfter int
+		endingBefore  int
+		want          []int
+		wantRequests  int
+	}{
+		{
+			name:         "one page",
+			want:         []int{0, 1, 2, 3, 4, 5, 6},
+			wantRequests: 1,
+		},
+		{
+			name:         "even pages",
+			limit:        7,
+			want:         []int{0, 1, 2, 3, 4, 5, 6},
+			wantRequests: 1,
+		},
+		{
+			name:         "several pages",
+			limit:        3,
+			want:         []int{0, 1, 2, 3, 4, 5, 6},
+			wantRequests: 3,
+		},
+		{
+			name:         "page per rule",
+			limit:        1,
+			want:         []int{0, 1, 2, 3, 4, 5, 6},
+			wantRequests: 7,
+		},
+		{
+			name:          "starting after",
+			limit:         2,
+			startingAfter: 2,
+			want:          []int{2, 3, 4, 5, 6},
+			wantRequests:  3,
+		},
+		{
+			name:         "ending before",
+			limit:        2,
+			endingBefore: 5,
+			want:         []int{0, 1, 2, 3},
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+
+			ids := []string{}
+			for i := 0; i < 7; i++ {
+				r := s.MustAddRule(t, easyredirtest.NewRule("https://example.com", fmt.Sprintf("old%d.com", i)))
+				ids = append(ids, r.Data.ID)
+			}
+
+			options := &easyredir.RulesOptions{Limit: tt.limit}
+			if tt.startingAfter > 0 {
+				options.StartingAfter = ids[tt.startingAfter-1]
+			}
+			if tt.endingBefore > 0 {
+				options.EndingBefore = ids[tt.endingBefore-1]
+			}
+
+			rules, err := c.ListRules(context.Background(), options)
+			if err != nil {
+				t.Fatalf("ListRules: %v", err)
+			}
+
+			got := []string{}
+			for _, r := range rules.Data {
+				got = append(got, r.ID)
+			}
+
+			want := []string{}
+			for _, i := range tt.want {
+				want = append(want, ids[i])
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("rules = %v, want %v", got, want)
+			}
+
+			if got := s.Requests(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestListHosts(t *testing.T) {
+	s, c := easyredirtest.NewClient(t)
+
+	want := []string{}
+	for i := 0; i < 5; i++ {
+		want = append(want, s.AddHost(fmt.Sprintf("host%d.com", i)).Data.ID)
+	}
+
+	hosts, err := c.ListHosts(context.Background(), &easyredir.HostsOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListHosts: %v", err)
+	}
+
+	got := []string{}
+	for _, h := range hosts.Data {
+		got = append(got, h.ID)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %v, want %v", got, want)
+	}
+}
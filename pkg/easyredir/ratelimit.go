@@ -0,0 +1,109 @@
+package easyredir
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Once fewer than this fraction of the quota remains, requests are spread
+	// evenly over the time left until the quota resets.
+	rateLimitPaceThreshold float64 = 0.1
+)
+
+// rateLimiter tracks the quota reported by the x-ratelimit-* response headers
+// and delays requests so that the quota is never exhausted.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func (l *rateLimiter) update(res *http.Response) {
+	limit, err := strconv.Atoi(res.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.remaining = remaining
+	l.reset = parseReset(res.Header.Get("X-RateLimit-Reset"), time.Now())
+
+	return
+}
+
+func (l *rateLimiter) wait(ctx context.Context, logger zerolog.Logger) error {
+	l.mu.Lock()
+	d := l.delay(time.Now())
+	if l.remaining > 0 {
+		l.remaining--
+	}
+	l.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	logger.Warn().Msg(fmt.Sprintf("Rate limit nearly exhausted, waiting %s.", d.Round(time.Millisecond)))
+
+	return sleep(ctx, d)
+}
+
+func (l *rateLimiter) delay(now time.Time) time.Duration {
+	if l.limit == 0 || l.reset.IsZero() || !now.Before(l.reset) {
+		return 0
+	}
+
+	until := l.reset.Sub(now)
+
+	if l.remaining <= 0 {
+		return until
+	}
+
+	if float64(l.remaining) >= float64(l.limit)*rateLimitPaceThreshold {
+		return 0
+	}
+
+	return until / time.Duration(l.remaining+1)
+}
+
+// parseReset accepts either a Unix timestamp or a number of seconds from now.
+func parseReset(v string, now time.Time) time.Time {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+
+	if n > 1000000000 {
+		return time.Unix(n, 0)
+	}
+
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
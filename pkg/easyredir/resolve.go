@@ -0,0 +1,118 @@
+package easyredir
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ResolveError is returned when a reference matches no resource, or more
+// than one. Candidates lists the closest matches as "id (description)".
+type ResolveError struct {
+	Resource   string
+	Reference  string
+	Candidates []string
+}
+
+func (e *ResolveError) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("no %s matches %q", e.Resource, e.Reference)
+	}
+
+	return fmt.Sprintf("%q does not identify a single %s, candidates: %s", e.Reference, e.Resource, strings.Join(e.Candidates, ", "))
+}
+
+// ResolveHost returns the ID of the host identified by ref, which is either
+// an ID or a host name.
+func (c *Client) ResolveHost(ctx context.Context, ref string) (id string, err error) {
+	if isID(ref) {
+		return ref, nil
+	}
+
+	hosts, err := c.ListHosts(ctx, &HostsOptions{})
+	if err != nil {
+		return "", fmt.Errorf("ResolveHost: %w", err)
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(ref, "."))
+	matches := []string{}
+
+	for _, h := range hosts.Data {
+		if strings.ToLower(h.Attributes.Name) == name {
+			matches = append(matches, fmt.Sprintf("%s (%s)", h.ID, h.Attributes.Name))
+			id = h.ID
+		}
+	}
+
+	if len(matches) != 1 {
+		return "", fmt.Errorf("ResolveHost: %w", &ResolveError{Resource: "host", Reference: ref, Candidates: matches})
+	}
+
+	return id, nil
+}
+
+// ResolveRule returns the ID of the rule identified by ref, which is either
+// an ID or one of the rule's source URLs. Rules whose source URLs only
+// contain ref are offered as candidates rather than matched.
+func (c *Client) ResolveRule(ctx context.Context, ref string) (id string, err error) {
+	if isID(ref) {
+		return ref, nil
+	}
+
+	rules, err := c.ListRules(ctx, &RulesOptions{SourceURL: ref})
+	if err != nil {
+		return "", fmt.Errorf("ResolveRule: %w", err)
+	}
+
+	exact := []string{}
+	candidates := []string{}
+
+	for _, r := range rules.Data {
+		for _, s := range r.Attributes.SourceURLs {
+			if sameSource(s, ref) {
+				exact = append(exact, fmt.Sprintf("%s (%s)", r.ID, s))
+				id = r.ID
+				break
+			}
+		}
+
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", r.ID, strings.Join(r.Attributes.SourceURLs, " ")))
+	}
+
+	if len(exact) == 1 {
+		return id, nil
+	}
+
+	if len(exact) > 1 {
+		candidates = exact
+	}
+
+	return "", fmt.Errorf("ResolveRule: %w", &ResolveError{Resource: "rule", Reference: ref, Candidates: candidates})
+}
+
+func isID(ref string) bool {
+	_, err := uuid.Parse(ref)
+
+	return err == nil
+}
+
+// sameSource compares source URLs ignoring the scheme, host case and the
+// trailing slash the API appends.
+func sameSource(a string, b string) bool {
+	normalize := func(s string) string {
+		if i := strings.Index(s, "://"); i != -1 {
+			s = s[i+3:]
+		}
+
+		host, path := s, ""
+		if i := strings.Index(s, "/"); i != -1 {
+			host, path = s[:i], s[i:]
+		}
+
+		return strings.ToLower(host) + strings.TrimRight(path, "/")
+	}
+
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,64 @@
+package easyredir
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries   int           = 5
+	defaultRetryWaitMin time.Duration = 1 * time.Second
+	defaultRetryWaitMax time.Duration = 30 * time.Second
+)
+
+// retryable reports whether a request should be sent again. Network errors,
+// rate limiting and server errors are retried, unless the request itself was
+// cancelled.
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff is exponential with jitter. When the API says when the quota
+// resets, that is honoured instead, still capped at RetryWaitMax.
+func (c *Client) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		now := time.Now()
+
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s > 0 {
+			return capWait(time.Duration(s)*time.Second, c.RetryWaitMax)
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			if reset := parseReset(res.Header.Get("X-RateLimit-Reset"), now); reset.After(now) {
+				return capWait(reset.Sub(now), c.RetryWaitMax)
+			}
+		}
+	}
+
+	wait := c.RetryWaitMin << attempt
+	if wait <= 0 || wait > c.RetryWaitMax {
+		wait = c.RetryWaitMax
+	}
+
+	half := wait / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func capWait(d time.Duration, max time.Duration) time.Duration {
+	if d > max {
+		return max
+	}
+
+	return d
+}
@@ -2,11 +2,13 @@ package easyredir
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -42,7 +44,7 @@ type Rule struct {
 			} `json:"source_hosts"`
 		} `json:"relationships"`
 	} `json:"data"`
-	Included interface{} `json:"included"`
+	Included []HostData `json:"included"`
 }
 
 type Rules struct {
@@ -72,6 +74,22 @@ type Rules struct {
 	Links Links `json:"links"`
 }
 
+// Rule returns the i-th rule of the list as a standalone Rule.
+func (r *Rules) Rule(i int) (rule Rule) {
+	d := r.Data[i]
+
+	rule.Data.ID = d.ID
+	rule.Data.Type = d.Type
+	rule.Data.Attributes.ForwardParams = d.Attributes.ForwardParams
+	rule.Data.Attributes.ForwardPath = d.Attributes.ForwardPath
+	rule.Data.Attributes.ResponseType = d.Attributes.ResponseType
+	rule.Data.Attributes.SourceUrls = d.Attributes.SourceURLs
+	rule.Data.Attributes.TargetURL = d.Attributes.TargetURL
+	rule.Data.Relationships = d.Relationships
+
+	return rule
+}
+
 type RulesOptions struct {
 	Limit         int    `json:"limit"`
 	StartingAfter string `json:"starting_after"`
@@ -80,63 +98,48 @@ type RulesOptions struct {
 	TargetURL     string `json:"tq"`
 }
 
-func (c *Client) ListRules(options *RulesOptions) (rules Rules, err error) {
-	limit := 100
-
-	var sourceURL string
-	var targetURL string
+// ListRules collects every page into memory. Use Rules to stream pages
+// instead.
+func (c *Client) ListRules(ctx context.Context, options *RulesOptions) (rules Rules, err error) {
+	p := c.Rules(ctx, options)
 
-	var startingAfter string
-	var endingBefore string
-
-	if options != nil {
-		sourceURL = options.SourceURL
-		targetURL = options.TargetURL
-	}
-
-	for {
-		res := Rules{}
-
-		req, err := http.NewRequest("GET", fmt.Sprintf("%s/rules?limit=%d&sq=%s&tq=%s&starting_after=%s&ending_before=%s", c.baseURL, limit, sourceURL, targetURL, startingAfter, endingBefore), nil)
-		if err != nil {
-			return rules, fmt.Errorf("ListRules: unable to create request: %w", err)
+	for p.Next() {
+		page := p.Page()
+		if p.Backward() {
+			rules.Data = append(page.Data, rules.Data...)
+			continue
 		}
+		rules.Data = append(rules.Data, page.Data...)
+	}
 
-		if err = c.sendRequest(req, &res); err != nil {
-			return rules, fmt.Errorf("ListRules: unable to send request: %w", err)
-		}
+	if err = p.Err(); err != nil {
+		return rules, fmt.Errorf("ListRules: %w", err)
+	}
 
-		rules.Data = append(rules.Data, res.Data...)
+	return rules, nil
+}
 
-		if res.Meta.HasMore == false {
-			break
-		}
+// GetRule fills in the rule with the ID already set, including its source
+// hosts.
+func (c *Client) GetRule(ctx context.Context, rule *Rule) (err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/rules/%s", c.baseURL, rule.Data.ID), nil)
+	if err != nil {
+		return fmt.Errorf("GetRule: unable to create request: %w", err)
+	}
 
-		if res.Links.Next != "" {
-			u, err := url.Parse(res.Links.Next)
-			if err != nil {
-				panic(err)
-			}
-			startingAfter = u.Query().Get("starting_after")
-		}
-		// if res.Links.Prev != "" {
-		// 	u, err := url.Parse(res.Links.Prev)
-		// 	if err != nil {
-		// 		panic(err)
-		// 	}
-		// 	endingBefore = u.Query().Get("ending_before")
-		// }
+	if err = c.sendRequest(req, &rule); err != nil {
+		return fmt.Errorf("GetRule: unable to send request: %w", err)
 	}
 
-	return rules, nil
+	return nil
 }
 
-func (c *Client) CreateRule(r *Rule) (rule *Rule, err error) {
+func (c *Client) CreateRule(ctx context.Context, r *Rule) (rule *Rule, err error) {
 	var buf bytes.Buffer
 
 	err = json.NewEncoder(&buf).Encode(r.Data.Attributes)
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/rules", c.baseURL), &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/rules", c.baseURL), &buf)
 	if err != nil {
 		return nil, fmt.Errorf("CreateRule: unable to create request: %w", err)
 	}
@@ -148,12 +151,12 @@ func (c *Client) CreateRule(r *Rule) (rule *Rule, err error) {
 	return rule, nil
 }
 
-func (c *Client) UpdateRule(r *Rule) (rule *Rule, err error) {
+func (c *Client) UpdateRule(ctx context.Context, r *Rule) (rule *Rule, err error) {
 	var buf bytes.Buffer
 
 	err = json.NewEncoder(&buf).Encode(r.Data.Attributes)
 
-	req, err := http.NewRequest("PATCH", fmt.Sprintf("%s/rules/%s", c.baseURL, r.Data.ID), &buf)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", fmt.Sprintf("%s/rules/%s", c.baseURL, r.Data.ID), &buf)
 	if err != nil {
 		return nil, fmt.Errorf("UpdateRule: unable to create request: %w", err)
 	}
@@ -165,8 +168,8 @@ func (c *Client) UpdateRule(r *Rule) (rule *Rule, err error) {
 	return rule, nil
 }
 
-func (c *Client) RemoveRule(r *Rule) (rule *Rule, err error) {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/rules/%s", c.baseURL, r.Data.ID), nil)
+func (c *Client) RemoveRule(ctx context.Context, r *Rule) (rule *Rule, err error) {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/rules/%s", c.baseURL, r.Data.ID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("RemoveRule: unable to create request: %w", err)
 	}
@@ -179,15 +182,8 @@ func (c *Client) RemoveRule(r *Rule) (rule *Rule, err error) {
 }
 
 func (r *Rules) Print() {
-	t := table.NewWriter()
-
-	t.SetStyle(table.StyleColoredBright)
-	t.Style().Options.DrawBorder = false
-	t.Style().Color = table.ColorOptions{}
-	t.Style().Box.PaddingLeft = ""
-	t.Style().Box.PaddingRight = "    "
-	t.Style().Color.Header = text.Colors{text.Bold}
-	t.SetOutputMirror(os.Stdout)
+	t := newTable(os.Stdout)
+
 	t.AppendHeader(table.Row{"ID", "SOURCE URLS", "TARGET URL"})
 	for _, h := range r.Data {
 		row := []table.Row{}
@@ -220,3 +216,49 @@ func (r *Rule) Print() {
 
 	return
 }
+
+func (r *Rules) columns() []string {
+	return []string{"ID", "SOURCE URLS", "TARGET URL", "RESPONSE TYPE", "FORWARD PARAMS", "FORWARD PATH", "SOURCE HOSTS"}
+}
+
+func (r *Rules) rows(sep string) (rows [][]string) {
+	for _, h := range r.Data {
+		hosts := []string{}
+		for _, sh := range h.Relationships.SourceHosts.Data {
+			hosts = append(hosts, sh.ID)
+		}
+
+		rows = append(rows, []string{
+			h.ID,
+			strings.Join(h.Attributes.SourceURLs, sep),
+			h.Attributes.TargetURL,
+			h.Attributes.ResponseType,
+			strconv.FormatBool(h.Attributes.ForwardParams),
+			strconv.FormatBool(h.Attributes.ForwardPath),
+			strings.Join(hosts, sep),
+		})
+	}
+
+	return rows
+}
+
+func (r *Rule) columns() []string {
+	return []string{"ID", "SOURCE URLS", "TARGET URL", "RESPONSE TYPE", "FORWARD PARAMS", "FORWARD PATH", "SOURCE HOSTS"}
+}
+
+func (r *Rule) rows(sep string) [][]string {
+	hosts := []string{}
+	for _, sh := range r.Data.Relationships.SourceHosts.Data {
+		hosts = append(hosts, sh.ID)
+	}
+
+	return [][]string{{
+		r.Data.ID,
+		strings.Join(r.Data.Attributes.SourceUrls, sep),
+		r.Data.Attributes.TargetURL,
+		r.Data.Attributes.ResponseType,
+		strconv.FormatBool(r.Data.Attributes.ForwardParams),
+		strconv.FormatBool(r.Data.Attributes.ForwardPath),
+		strings.Join(hosts, sep),
+	}}
+}
@@ -0,0 +1,369 @@
+package easyredir
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/jedib0t/go-pretty/v6/text"
+
+	_ "embed"
+)
+
+//go:embed resolution_print.tmpl
+var resolutionPrintTemplate string
+
+const (
+	ReasonRule         string = "rule"
+	ReasonHTTPSUpgrade string = "https_upgrade"
+	ReasonNotFound     string = "not_found_action"
+	ReasonUnknownHost  string = "unknown_host"
+)
+
+// responseTypes maps rule response types to the status code served.
+var responseTypes = map[string]int{
+	"moved_permanently":  http.StatusMovedPermanently,
+	"found":              http.StatusFound,
+	"temporary_redirect": http.StatusTemporaryRedirect,
+	"permanent_redirect": http.StatusPermanentRedirect,
+}
+
+// Simulator evaluates requests against a set of rules and host settings
+// without contacting the API. Hosts are matched by name and should carry the
+// full settings returned by GetHost.
+type Simulator struct {
+	Rules []Rule
+	Hosts []Host
+}
+
+// Resolution is the response EasyRedir would give to a request.
+type Resolution struct {
+	URL        string
+	StatusCode int
+	Location   string
+	Reason     string
+	RuleID     string
+	SourceURL  string
+	Host       string
+}
+
+// Simulator loads rules and host settings from the account. Given hostnames
+// it loads only those hosts, otherwise the whole account. A host that does
+// not exist yet is not an error; requests to it resolve as unknown.
+func (c *Client) Simulator(ctx context.Context, hostnames ...string) (*Simulator, error) {
+	s := &Simulator{}
+
+	if len(hostnames) == 0 {
+		rules, err := c.ListRules(ctx, &RulesOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Simulator: %w", err)
+		}
+
+		for i := range rules.Data {
+			s.Rules = append(s.Rules, rules.Rule(i))
+		}
+
+		hosts, err := c.ListHosts(ctx, &HostsOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Simulator: %w", err)
+		}
+
+		for _, h := range hosts.Data {
+			host := Host{}
+			host.Data.ID = h.ID
+			if err = c.GetHost(ctx, &host); err != nil {
+				return nil, fmt.Errorf("Simulator: %w", err)
+			}
+			s.Hosts = append(s.Hosts, host)
+		}
+
+		return s, nil
+	}
+
+	// A rule with sources on several of the hosts is listed for each.
+	seen := make(map[string]bool)
+
+	for _, hostname := range hostnames {
+		rules, err := c.ListRules(ctx, &RulesOptions{SourceURL: hostname})
+		if err != nil {
+			return nil, fmt.Errorf("Simulator: %w", err)
+		}
+
+		for i := range rules.Data {
+			if seen[rules.Data[i].ID] {
+				continue
+			}
+			seen[rules.Data[i].ID] = true
+
+			s.Rules = append(s.Rules, rules.Rule(i))
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, hostname := range hostnames {
+		wanted[strings.ToLower(hostname)] = true
+	}
+
+	hosts, err := c.ListHosts(ctx, &HostsOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Simulator: %w", err)
+	}
+
+	for _, h := range hosts.Data {
+		name := strings.ToLower(h.Attributes.Name)
+		if !wanted[name] {
+			continue
+		}
+		delete(wanted, name)
+
+		host := Host{}
+		host.Data.ID = h.ID
+		if err = c.GetHost(ctx, &host); err != nil {
+			return nil, fmt.Errorf("Simulator: %w", err)
+		}
+		s.Hosts = append(s.Hosts, host)
+	}
+
+	return s, nil
+}
+
+// Resolve follows the order EasyRedir applies: HTTPS upgrade, then an exact
+// source URL match, then the longest source URL that is a path prefix of a
+// rule forwarding the path, and finally the host not found action.
+func (s *Simulator) Resolve(rawURL string) (res Resolution, err error) {
+	u, err := parseSource(rawURL)
+	if err != nil {
+		return res, fmt.Errorf("Resolve: %w", err)
+	}
+
+	res.URL = u.String()
+	res.Host = strings.ToLower(u.Hostname())
+
+	host := s.host(res.Host)
+
+	var caseInsensitive, slashInsensitive bool
+	if host != nil {
+		a := host.Data.Attributes
+		caseInsensitive = boolValue(a.MatchOptions.CaseInsensitive)
+		slashInsensitive = boolValue(a.MatchOptions.SlashInsensitive)
+
+		if u.Scheme == "http" && boolValue(a.Security.HTTPSUpgrade) {
+			upgraded := *u
+			upgraded.Scheme = "https"
+
+			res.StatusCode = http.StatusMovedPermanently
+			res.Location = upgraded.String()
+			res.Reason = ReasonHTTPSUpgrade
+
+			return res, nil
+		}
+	}
+
+	rule, source, remainder := s.match(u, caseInsensitive, slashInsensitive)
+
+	if rule != nil {
+		a := rule.Data.Attributes
+
+		res.Reason = ReasonRule
+		res.RuleID = rule.Data.ID
+		res.SourceURL = source
+		res.StatusCode = responseTypes[a.ResponseType]
+		if res.StatusCode == 0 {
+			res.StatusCode = http.StatusMovedPermanently
+		}
+
+		if !a.ForwardPath {
+			remainder = ""
+		}
+
+		res.Location, err = target(a.TargetURL, remainder, u.RawQuery, a.ForwardParams)
+		if err != nil {
+			return res, fmt.Errorf("Resolve: rule %s: %w", rule.Data.ID, err)
+		}
+
+		return res, nil
+	}
+
+	if host == nil {
+		res.StatusCode = http.StatusNotFound
+		res.Reason = ReasonUnknownHost
+
+		return res, nil
+	}
+
+	nf := host.Data.Attributes.NotFoundAction
+
+	res.Reason = ReasonNotFound
+	res.StatusCode = nf.ResponseCode
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusNotFound
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return res, nil
+	}
+
+	responseURL := fmt.Sprint(nf.ResponseURL)
+	if nf.ResponseURL == nil || responseURL == "" {
+		return res, nil
+	}
+
+	path := ""
+	if boolValue(nf.ForwardPath) {
+		path = u.EscapedPath()
+	}
+
+	res.Location, err = target(responseURL, path, u.RawQuery, boolValue(nf.ForwardParams))
+	if err != nil {
+		return res, fmt.Errorf("Resolve: host %s: %w", res.Host, err)
+	}
+
+	return res, nil
+}
+
+func (s *Simulator) host(name string) *Host {
+	for i, h := range s.Hosts {
+		if strings.EqualFold(h.Data.Attributes.Name, name) {
+			return &s.Hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// match returns the best rule for the request along with the source URL that
+// matched and the part of the request path beyond it.
+func (s *Simulator) match(u *url.URL, caseInsensitive bool, slashInsensitive bool) (best *Rule, source string, remainder string) {
+	bestLen := -1
+
+	requestPath := u.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+
+	normalize := func(p string) string {
+		if caseInsensitive {
+			p = strings.ToLower(p)
+		}
+		if slashInsensitive && p != "/" {
+			p = strings.TrimRight(p, "/")
+		}
+		return p
+	}
+
+	rp := normalize(requestPath)
+
+	for i, r := range s.Rules {
+		for _, src := range r.Data.Attributes.SourceUrls {
+			su, err := parseSource(src)
+			if err != nil || !strings.EqualFold(su.Hostname(), u.Hostname()) {
+				continue
+			}
+
+			if strings.Contains(src, "://") && su.Scheme != u.Scheme {
+				continue
+			}
+
+			if su.RawQuery != "" && su.Query().Encode() != u.Query().Encode() {
+				continue
+			}
+
+			sourcePath := su.EscapedPath()
+			if sourcePath == "" {
+				sourcePath = "/"
+			}
+			sp := normalize(sourcePath)
+
+			if sp == rp {
+				return &s.Rules[i], src, ""
+			}
+
+			if !r.Data.Attributes.ForwardPath || !strings.HasPrefix(rp, sp) || len(sp) <= bestLen {
+				continue
+			}
+
+			if !strings.HasSuffix(sp, "/") && rp[len(sp)] != '/' {
+				continue
+			}
+
+			best, source, bestLen = &s.Rules[i], src, len(sp)
+			remainder = requestPath[len(sp):]
+		}
+	}
+
+	return best, source, remainder
+}
+
+// target appends the forwarded path remainder and query string to the
+// redirect target.
+func target(rawURL string, remainder string, query string, forwardParams bool) (string, error) {
+	t, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid target URL %s: %w", rawURL, err)
+	}
+
+	if remainder = strings.TrimLeft(remainder, "/"); remainder != "" {
+		p, err := url.PathUnescape(remainder)
+		if err != nil {
+			p = remainder
+		}
+		t.Path = strings.TrimRight(t.Path, "/") + "/" + p
+		t.RawPath = ""
+	}
+
+	if forwardParams && query != "" {
+		if t.RawQuery != "" {
+			t.RawQuery += "&" + query
+		} else {
+			t.RawQuery = query
+		}
+	}
+
+	return t.String(), nil
+}
+
+// parseSource accepts URLs with or without a scheme, defaulting to http.
+func parseSource(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", s, err)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %s: missing host", s)
+	}
+
+	return u, nil
+}
+
+func boolValue(v interface{}) bool {
+	b, ok := v.(bool)
+
+	return ok && b
+}
+
+func (r *Resolution) Print() {
+	fmt.Printf("%s:\n", text.FgBlue.Sprint("RESOLUTION"))
+	fmt.Println()
+
+	var w bytes.Buffer
+
+	t := template.Must(template.New("").Parse(resolutionPrintTemplate))
+	t.Execute(&w, r)
+
+	quick.Highlight(os.Stdout, w.String(), "yaml", "terminal256", "pygments")
+
+	fmt.Println()
+
+	return
+}
@@ -0,0 +1,248 @@
+package easyredir_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir"
+	"github.com/mikelorant/easyredir-cli/pkg/easyredir/easyredirtest"
+)
+
+func simulatorRule(id string, target string, responseType string, forwardPath bool, forwardParams bool, sources ...string) easyredir.Rule {
+	r := easyredir.Rule{}
+	r.Data.ID = id
+	r.Data.Attributes.SourceUrls = sources
+	r.Data.Attributes.TargetURL = target
+	r.Data.Attributes.ResponseType = responseType
+	r.Data.Attributes.ForwardPath = forwardPath
+	r.Data.Attributes.ForwardParams = forwardParams
+
+	return r
+}
+
+func simulatorHost(name string) easyredir.Host {
+	h := easyredir.Host{}
+	h.Data.Attributes.Name = name
+
+	return h
+}
+
+func TestResolve(t *testing.T) {
+	upgrade := simulatorHost("secure.com")
+	upgrade.Data.Attributes.Security.HTTPSUpgrade = true
+
+	insensitive := simulatorHost("loose.com")
+	insensitive.Data.Attributes.MatchOptions.CaseInsensitive = true
+	insensitive.Data.Attributes.MatchOptions.SlashInsensitive = true
+
+	notFound := simulatorHost("moved.com")
+	notFound.Data.Attributes.NotFoundAction.ResponseCode = http.StatusFound
+	notFound.Data.Attributes.NotFoundAction.ResponseURL = "https://fallback.com"
+	notFound.Data.Attributes.NotFoundAction.ForwardPath = true
+	notFound.Data.Attributes.NotFoundAction.ForwardParams = true
+
+	s := &easyredir.Simulator{
+		Rules: []easyredir.Rule{
+			simulatorRule("exact", "https://new.com/about", "found", false, false, "old.com/about"),
+			simulatorRule("prefix", "https://new.com/docs", "moved_permanently", true, true, "old.com/docs"),
+			simulatorRule("deeper", "https://new.com/api", "permanent_redirect", true, false, "old.com/docs/api"),
+			simulatorRule("root", "https://new.com", "temporary_redirect", false, false, "old.com"),
+			simulatorRule("https", "https://tls.com", "found", false, false, "https://scheme.com/"),
+			simulatorRule("loose", "https://new.com/loose", "found", false, false, "loose.com/Path/"),
+			simulatorRule("secure", "https://new.com/secure", "found", false, false, "secure.com/page"),
+		},
+		Hosts: []easyredir.Host{upgrade, insensitive, notFound, simulatorHost("old.com")},
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantLoc    string
+		wantReason string
+		wantRule   string
+	}{
+		{
+			name:       "exact match",
+			url:        "http://old.com/about",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://new.com/about",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "exact",
+		},
+		{
+			name:       "exact match ignores path forwarding",
+			url:        "http://old.com/about?x=1",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://new.com/about",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "exact",
+		},
+		{
+			name:       "prefix forwards path and query",
+			url:        "http://old.com/docs/guide/intro?lang=en",
+			wantStatus: http.StatusMovedPermanently,
+			wantLoc:    "https://new.com/docs/guide/intro?lang=en",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "prefix",
+		},
+		{
+			name:       "longest prefix wins",
+			url:        "http://old.com/docs/api/v1",
+			wantStatus: http.StatusPermanentRedirect,
+			wantLoc:    "https://new.com/api/v1",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "deeper",
+		},
+		{
+			name:       "prefix only at a path boundary",
+			url:        "http://old.com/docsearch",
+			wantStatus: http.StatusNotFound,
+			wantReason: easyredir.ReasonNotFound,
+		},
+		{
+			name:       "bare host",
+			url:        "old.com",
+			wantStatus: http.StatusTemporaryRedirect,
+			wantLoc:    "https://new.com",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "root",
+		},
+		{
+			name:       "scheme must match",
+			url:        "http://scheme.com/",
+			wantStatus: http.StatusNotFound,
+			wantReason: easyredir.ReasonUnknownHost,
+		},
+		{
+			name:       "scheme matches",
+			url:        "https://scheme.com/",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://tls.com",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "https",
+		},
+		{
+			name:       "case and slash insensitive",
+			url:        "http://LOOSE.com/path",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://new.com/loose",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "loose",
+		},
+		{
+			name:       "https upgrade before rules",
+			url:        "http://secure.com/page",
+			wantStatus: http.StatusMovedPermanently,
+			wantLoc:    "https://secure.com/page",
+			wantReason: easyredir.ReasonHTTPSUpgrade,
+		},
+		{
+			name:       "rule after https upgrade",
+			url:        "https://secure.com/page",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://new.com/secure",
+			wantReason: easyredir.ReasonRule,
+			wantRule:   "secure",
+		},
+		{
+			name:       "not found action",
+			url:        "http://moved.com/some/path?q=1",
+			wantStatus: http.StatusFound,
+			wantLoc:    "https://fallback.com/some/path?q=1",
+			wantReason: easyredir.ReasonNotFound,
+		},
+		{
+			name:       "unknown host",
+			url:        "http://nowhere.com/",
+			wantStatus: http.StatusNotFound,
+			wantReason: easyredir.ReasonUnknownHost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Resolve(tt.url)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if res.Location != tt.wantLoc {
+				t.Errorf("location = %q, want %q", res.Location, tt.wantLoc)
+			}
+			if res.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", res.Reason, tt.wantReason)
+			}
+			if res.RuleID != tt.wantRule {
+				t.Errorf("rule = %q, want %q", res.RuleID, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestResolveInvalidURL(t *testing.T) {
+	s := &easyredir.Simulator{}
+
+	if _, err := s.Resolve("http://"); err == nil {
+		t.Fatal("Resolve: want an error for a URL without a host")
+	}
+}
+
+func TestClientSimulator(t *testing.T) {
+	tests := []struct {
+		name         string
+		hostnames    []string
+		wantRules    int
+		wantHosts    int
+		wantRequests int
+	}{
+		{
+			name:         "rule spanning hosts",
+			hostnames:    []string{"a.com", "b.com"},
+			wantRules:    2,
+			wantHosts:    2,
+			wantRequests: 5,
+		},
+		{
+			name:         "repeated and unknown hosts",
+			hostnames:    []string{"a.com", "A.com", "new.com"},
+			wantRules:    2,
+			wantHosts:    1,
+			wantRequests: 5,
+		},
+		{
+			name:         "whole account",
+			wantRules:    3,
+			wantHosts:    3,
+			wantRequests: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := easyredirtest.NewClient(t)
+			s.MustAddRule(t, easyredirtest.NewRule("https://new.com/ab", "a.com/x", "b.com/x"))
+			s.MustAddRule(t, easyredirtest.NewRule("https://new.com/a", "a.com/y"))
+			s.MustAddRule(t, easyredirtest.NewRule("https://new.com/c", "c.com/z"))
+
+			sim, err := c.Simulator(context.Background(), tt.hostnames...)
+			if err != nil {
+				t.Fatalf("Simulator: %v", err)
+			}
+
+			if len(sim.Rules) != tt.wantRules {
+				t.Errorf("rules = %d, want %d", len(sim.Rules), tt.wantRules)
+			}
+			if len(sim.Hosts) != tt.wantHosts {
+				t.Errorf("hosts = %d, want %d", len(sim.Hosts), tt.wantHosts)
+			}
+			if got := s.Requests(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}